@@ -65,6 +65,10 @@ func mainInternal() error {
 }
 
 func execWorkflow(urlPath string, workflow *config.Workflow) error {
+	// the server cannot read the local template files
+	if err := workflow.BundleTemplates(); err != nil {
+		return err
+	}
 
 	data, err := yaml.Marshal(workflow)
 	if err != nil {
@@ -21,6 +21,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	log "k8s.io/klog/v2"
 
@@ -38,13 +39,16 @@ type Args struct {
 }
 
 func mainInternal() error {
+	if len(os.Args) > 1 && os.Args[1] == "cleanup" {
+		return cleanup(os.Args[2:])
+	}
+
 	var args Args
-	flag.StringVar(&args.kubeCfg.KubeConfigPath, "kubeconfig", "", "kubeconfig file path")
-	flag.StringVar(&args.kubeCfg.KubeCtx, "kubectx", "", "kube context")
-	flag.Float64Var(&args.kubeCfg.QPS, "kube-api-qps", 500, "Maximum QPS to use while talking with Kubernetes API")
-	flag.IntVar(&args.kubeCfg.Burst, "kube-api-burst", 500, "Maximum burst for throttle while talking with Kubernetes API")
+	addKubeFlags(flag.CommandLine, &args.kubeCfg)
 	flag.BoolVar(&args.cleanupInfo.Enabled, "cleanup", false, "delete objects")
-	flag.DurationVar(&args.cleanupInfo.Timeout, "cleanup.timeout", engine.DefaultCleanupTimeout, "time limit for cleanup")
+	flag.DurationVar(&args.cleanupInfo.Timeout, "cleanup.timeout", engine.DefaultCleanupTimeout, "time limit for cleanup, and separately for the wait with cleanup.wait")
+	flag.BoolVar(&args.cleanupInfo.Wait, "cleanup.wait", false, "wait until the deleted objects and their pods are gone")
+	flag.StringVar(&args.cleanupInfo.PropagationPolicy, "cleanup.propagation", "Background", "deletion propagation policy: Foreground, Background or Orphan")
 	flag.StringVar(&args.workflow, "workflow", "", "comma-separated list of workflow config files and dirs (mutually exclusive with the 'port' flag)")
 	flag.IntVar(&args.port, "port", 0, "listening port (mutually exclusive with the 'workflow' flag)")
 
@@ -87,6 +91,52 @@ func mainInternal() error {
 	return nil
 }
 
+// cleanup deletes the objects created by knavigator, found by their tracking labels
+func cleanup(argv []string) error {
+	var kubeCfg config.KubeConfig
+	var opts engine.GCOptions
+	var timeout time.Duration
+
+	fs := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s cleanup --run-id <run ID> | --all [options]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	addKubeFlags(fs, &kubeCfg)
+	fs.StringVar(&opts.RunID, "run-id", "", "delete the objects of the workflow run (mutually exclusive with the 'all' flag)")
+	fs.BoolVar(&opts.All, "all", false, "delete the objects of all workflow runs (mutually exclusive with the 'run-id' flag)")
+	fs.BoolVar(&opts.Configured, "configured", false, "also delete the objects created by Configure tasks, such as virtual nodes, namespaces and priority classes")
+	fs.DurationVar(&timeout, "timeout", engine.DefaultCleanupTimeout, "time limit for cleanup")
+
+	log.InitFlags(fs)
+	if err := fs.Parse(argv); err != nil {
+		return err
+	}
+
+	if err := opts.Validate(); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	restConfig, err := utils.GetK8sConfig(&kubeCfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return engine.CollectGarbage(ctx, restConfig, &opts)
+}
+
+// addKubeFlags adds the Kubernetes client flags to the flag set
+func addKubeFlags(fs *flag.FlagSet, kubeCfg *config.KubeConfig) {
+	fs.StringVar(&kubeCfg.KubeConfigPath, "kubeconfig", "", "kubeconfig file path")
+	fs.StringVar(&kubeCfg.KubeCtx, "kubectx", "", "kube context")
+	fs.Float64Var(&kubeCfg.QPS, "kube-api-qps", 500, "Maximum QPS to use while talking with Kubernetes API")
+	fs.IntVar(&kubeCfg.Burst, "kube-api-burst", 500, "Maximum burst for throttle while talking with Kubernetes API")
+}
+
 func validate(args *Args) error {
 	if len(args.workflow) == 0 && args.port == 0 {
 		return fmt.Errorf("must specify 'workflow' or 'port'")
@@ -96,6 +146,10 @@ func validate(args *Args) error {
 		return fmt.Errorf("'workflow' and 'port' are mutually exclusive")
 	}
 
+	if err := args.cleanupInfo.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
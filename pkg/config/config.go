@@ -33,9 +33,17 @@ type KubeConfig struct {
 }
 
 type Workflow struct {
-	Name        string  `yaml:"name"`
-	Description string  `yaml:"description,omitempty"`
-	Tasks       []*Task `yaml:"tasks"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	// Seed: an optional seed for all random choices made by the workflow; random if not set
+	Seed *int64 `yaml:"seed,omitempty"`
+	// ObjectIDStart: the object ID counter ('_ENUM_') of the run starts after this value; default 0
+	ObjectIDStart int64 `yaml:"objectIdStart,omitempty"`
+	// RunID: an optional ID of the run, set in the tracking labels of the submitted objects; generated if not set
+	RunID string  `yaml:"runId,omitempty"`
+	Tasks []*Task `yaml:"tasks"`
+	// Templates: template files bundled with the workflow for remote execution, keyed by the template reference
+	Templates map[string]string `yaml:"templates,omitempty"`
 }
 
 type Task struct {
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// templateParam is the task parameter referring to the object template
+	templateParam = "template"
+	// manifestsParam is the Configure task parameter listing the manifests, which refer to templates
+	manifestsParam = "manifests"
+)
+
+// IsInlineTemplate returns true if the template reference is an inline YAML manifest rather than a path
+func IsInlineTemplate(ref string) bool {
+	return strings.Contains(strings.TrimSpace(ref), "\n")
+}
+
+// LoadTemplate returns the content of the template referred to by a file path, a directory, or a glob pattern.
+// The manifests from a directory or a glob pattern are concatenated in name order as a multi-document YAML.
+// Inline templates are returned as is.
+func LoadTemplate(ref string) (string, error) {
+	if IsInlineTemplate(ref) {
+		return ref, nil
+	}
+
+	var files []string
+	if strings.ContainsAny(ref, "*?[") {
+		var err error
+		if files, err = filepath.Glob(ref); err != nil {
+			return "", fmt.Errorf("invalid template pattern %s: %v", ref, err)
+		}
+	} else if info, err := os.Stat(ref); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(ref)
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range entries {
+			if ext := filepath.Ext(entry.Name()); !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, filepath.Join(ref, entry.Name()))
+			}
+		}
+	} else {
+		data, err := os.ReadFile(filepath.Clean(ref))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	if len(files) == 0 {
+		return "", fmt.Errorf("no template files found in %s", ref)
+	}
+	sort.Strings(files)
+
+	docs := make([]string, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Clean(file))
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, strings.TrimSuffix(string(data), "\n"))
+	}
+
+	return strings.Join(docs, "\n---\n") + "\n", nil
+}
+
+// BundleTemplates reads the template files referred to by the tasks and adds them to the workflow,
+// so that the workflow can be executed by a remote server
+func (c *Workflow) BundleTemplates() error {
+	for _, task := range c.Tasks {
+		for _, ref := range templateRefs(task.Params) {
+			if len(ref) == 0 || IsInlineTemplate(ref) {
+				continue
+			}
+			if _, ok := c.Templates[ref]; ok {
+				continue
+			}
+
+			data, err := LoadTemplate(ref)
+			if err != nil {
+				return fmt.Errorf("%s: failed to bundle template: %v", task.ID, err)
+			}
+
+			if c.Templates == nil {
+				c.Templates = make(map[string]string)
+			}
+			c.Templates[ref] = data
+		}
+	}
+
+	return nil
+}
+
+// templateRefs returns the template references in the task parameters
+func templateRefs(params map[string]interface{}) []string {
+	refs := []string{}
+	if ref, ok := params[templateParam].(string); ok {
+		refs = append(refs, ref)
+	}
+	manifests, _ := params[manifestsParam].([]interface{})
+	for _, m := range manifests {
+		var ref interface{}
+		switch v := m.(type) {
+		case map[string]interface{}:
+			ref = v[templateParam]
+		case map[interface{}]interface{}:
+			ref = v[templateParam]
+		}
+		if s, ok := ref.(string); ok {
+			refs = append(refs, s)
+		}
+	}
+	return refs
+}
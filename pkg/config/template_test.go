@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTemplate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("kind: B\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yml"), []byte("kind: A\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("text\n"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "empty"), 0700))
+
+	inline := "apiVersion: v1\nkind: ConfigMap\n"
+
+	testCases := []struct {
+		name string
+		ref  string
+		data string
+		err  string
+	}{
+		{
+			name: "Case 1: inline template",
+			ref:  inline,
+			data: inline,
+		},
+		{
+			name: "Case 2: file",
+			ref:  filepath.Join(dir, "b.yaml"),
+			data: "kind: B\n",
+		},
+		{
+			name: "Case 3: directory",
+			ref:  dir,
+			data: "kind: A\n---\nkind: B\n",
+		},
+		{
+			name: "Case 4: glob",
+			ref:  filepath.Join(dir, "*.yaml"),
+			data: "kind: B\n",
+		},
+		{
+			name: "Case 5: empty directory",
+			ref:  filepath.Join(dir, "empty"),
+			err:  "no template files found in " + filepath.Join(dir, "empty"),
+		},
+		{
+			name: "Case 6: missing file",
+			ref:  "/does/not/exist",
+			err:  "open /does/not/exist: no such file or directory",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := LoadTemplate(tc.ref)
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.data, data)
+			}
+		})
+	}
+}
+
+func TestBundleTemplates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "job.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("kind: Job\n"), 0600))
+	quota := filepath.Join(dir, "quota.yaml")
+	require.NoError(t, os.WriteFile(quota, []byte("kind: ResourceQuota\n"), 0600))
+
+	workflow := &Workflow{
+		Name: "test",
+		Tasks: []*Task{
+			{ID: "register", Type: "RegisterObj", Params: map[string]interface{}{"template": path}},
+			{ID: "inline", Type: "RegisterObj", Params: map[string]interface{}{"template": "kind: Job\nmetadata: {}\n"}},
+			{ID: "sleep", Type: "Sleep", Params: map[string]interface{}{"timeout": "1s"}},
+			{ID: "configure", Type: "Configure", Params: map[string]interface{}{
+				"manifests": []interface{}{map[string]interface{}{"template": quota, "op": "create"}},
+			}},
+		},
+	}
+	require.NoError(t, workflow.BundleTemplates())
+	require.Equal(t, map[string]string{path: "kind: Job\n", quota: "kind: ResourceQuota\n"}, workflow.Templates)
+
+	workflow.Tasks[0].Params["template"] = "/does/not/exist"
+	require.EqualError(t, workflow.BundleTemplates(),
+		"register: failed to bundle template: open /does/not/exist: no such file or directory")
+}
@@ -18,7 +18,10 @@ package engine
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"time"
 
 	"gopkg.in/yaml.v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,6 +31,15 @@ import (
 	"github.com/NVIDIA/knavigator/pkg/config"
 )
 
+// configmap value formats
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+
+	// configmapPollInterval is the interval between the configmap checks
+	configmapPollInterval = time.Second
+)
+
 // CheckConfigmapTask represents a task that checks content of a configmap.
 type CheckConfigmapTask struct {
 	BaseTask
@@ -41,6 +53,11 @@ type checkConfigmapTaskParams struct {
 	Namespace string            `yaml:"namespace"`
 	Data      map[string]string `yaml:"data"`
 	Op        string            `yaml:"op"`
+	// Format: if set to "yaml" or "json", the values are parsed as documents and compared semantically;
+	// with "subset" operation, the expected documents must be subsets of the actual ones, see isSubDocument
+	Format string `yaml:"format,omitempty"`
+	// Timeout: if set, the configmap is checked until it matches, or until the timeout
+	Timeout time.Duration `yaml:"timeout,omitempty"`
 }
 
 // newCheckConfigmapTask initializes and returns CheckPodTask
@@ -84,11 +101,54 @@ func (task *CheckConfigmapTask) validate(params map[string]interface{}) error {
 		return fmt.Errorf("%s: invalid configmap operation %s; supported: %s, %s", task.ID(), task.Op, OpCmpEqual, OpCmpSubset)
 	}
 
+	switch task.Format {
+	case "":
+		// nop
+	case FormatYAML, FormatJSON:
+		for key, val := range task.Data {
+			if _, err = parseDocument(task.Format, val); err != nil {
+				return fmt.Errorf("%s: failed to parse expected value for key %q: %v", task.ID(), key, err)
+			}
+		}
+	default:
+		return fmt.Errorf("%s: invalid format %s; supported: %s, %s", task.ID(), task.Format, FormatYAML, FormatJSON)
+	}
+
 	return nil
 }
 
 // Exec implements Runnable interface
 func (task *CheckConfigmapTask) Exec(ctx context.Context) error {
+	// Check once and return if timeout is not set
+	if task.Timeout == 0 {
+		return task.check(ctx)
+	}
+
+	// Keep checking until timeout, since the configmaps can be written asynchronously by controllers
+	pollCtx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	for {
+		err := task.check(pollCtx)
+		if err == nil {
+			return nil
+		}
+		log.V(4).Infof("%v", err)
+
+		select {
+		case <-pollCtx.Done():
+			// recheck the configmap to report its current state
+			if err = task.check(ctx); err != nil {
+				err = fmt.Errorf("%w: %w", pollCtx.Err(), err)
+			}
+			return err
+		case <-time.After(configmapPollInterval):
+		}
+	}
+}
+
+// check gets the configmap and compares its data with the expected one
+func (task *CheckConfigmapTask) check(ctx context.Context) error {
 	cm, err := task.client.CoreV1().ConfigMaps(task.Namespace).Get(ctx, task.Name, metav1.GetOptions{})
 	if err != nil {
 		return fmt.Errorf("%s: failed to get configmap %s/%s: %v", task.ID(), task.Namespace, task.Name, err)
@@ -107,7 +167,11 @@ func (task *CheckConfigmapTask) compareConfigMaps(data map[string]string) error
 		if !ok {
 			return fmt.Errorf("%s: configmap %s/%s does not have key %q", task.ID(), task.Namespace, task.Name, key)
 		}
-		if expected != actual {
+		match, err := task.compareValues(expected, actual)
+		if err != nil {
+			return fmt.Errorf("%s: configmap %s/%s: failed to parse value for key %q: %v", task.ID(), task.Namespace, task.Name, key, err)
+		}
+		if !match {
 			log.V(4).Infof("configmap mismatch: key: %q actual: %q expected: %q", key, actual, expected)
 			return fmt.Errorf("%s: configmap %s/%s does not match value for key %q", task.ID(), task.Namespace, task.Name, key)
 		}
@@ -115,3 +179,77 @@ func (task *CheckConfigmapTask) compareConfigMaps(data map[string]string) error
 
 	return nil
 }
+
+// compareValues compares the configmap values as strings, or as documents if the format is set
+func (task *CheckConfigmapTask) compareValues(expected, actual string) (bool, error) {
+	if len(task.Format) == 0 {
+		return expected == actual, nil
+	}
+
+	expectedDoc, err := parseDocument(task.Format, expected)
+	if err != nil {
+		return false, err
+	}
+	actualDoc, err := parseDocument(task.Format, actual)
+	if err != nil {
+		return false, err
+	}
+
+	if task.Op == OpCmpEqual {
+		return reflect.DeepEqual(expectedDoc, actualDoc), nil
+	}
+	return isSubDocument(actualDoc, expectedDoc), nil
+}
+
+// isSubDocument returns true if sub is a subset of doc: the maps must contain the expected keys,
+// or lack the keys with null values, the lists must have the same length and their elements must be subsets
+// of the actual elements, and the scalars must be equal
+func isSubDocument(doc, sub interface{}) bool {
+	switch subVal := sub.(type) {
+	case map[string]interface{}:
+		docVal, ok := doc.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, val := range subVal {
+			actual, ok := docVal[key]
+			if val == nil {
+				if ok {
+					return false
+				}
+				continue
+			}
+			if !ok || !isSubDocument(actual, val) {
+				return false
+			}
+		}
+		return true
+
+	case []interface{}:
+		docVal, ok := doc.([]interface{})
+		if !ok || len(docVal) != len(subVal) {
+			return false
+		}
+		for i := range subVal {
+			if !isSubDocument(docVal[i], subVal[i]) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return reflect.DeepEqual(doc, sub)
+	}
+}
+
+// parseDocument parses the YAML or JSON document
+func parseDocument(format, value string) (interface{}, error) {
+	var doc interface{}
+	var err error
+	if format == FormatJSON {
+		err = json.Unmarshal([]byte(value), &doc)
+	} else {
+		err = yaml.Unmarshal([]byte(value), &doc)
+	}
+	return doc, err
+}
@@ -47,6 +47,30 @@ func TestCheckConfigmapTask(t *testing.T) {
 			},
 			err: "CheckConfigmap/check: invalid configmap operation BAD; supported: equal, subset",
 		},
+		{
+			name:       "Case 4a: invalid format",
+			simClients: true,
+			params: map[string]interface{}{
+				"name":      "test",
+				"namespace": "default",
+				"data":      map[string]string{"key": "val"},
+				"op":        "equal",
+				"format":    "xml",
+			},
+			err: "CheckConfigmap/check: invalid format xml; supported: yaml, json",
+		},
+		{
+			name:       "Case 4b: invalid expected document",
+			simClients: true,
+			params: map[string]interface{}{
+				"name":      "test",
+				"namespace": "default",
+				"data":      map[string]string{"key": "{bad"},
+				"op":        "equal",
+				"format":    "json",
+			},
+			err: `CheckConfigmap/check: failed to parse expected value for key "key": invalid character 'b' looking for beginning of object key string`,
+		},
 		{
 			name:       "Case 5: valid input",
 			simClients: true,
@@ -166,6 +190,93 @@ func TestCompareConfigMaps(t *testing.T) {
 			},
 			actual: map[string]string{"a": "b", "c": "d", "e": "f"},
 		},
+		{
+			name: "Case 6: valid OpCmpEqual case with YAML format",
+			task: &CheckConfigmapTask{
+				BaseTask: BaseTask{taskType: TaskCheckConfigmap, taskID: taskID},
+				checkConfigmapTaskParams: checkConfigmapTaskParams{
+					Name:      "test",
+					Namespace: "default",
+					Data:      map[string]string{"config.yaml": "kind: Configuration\nwaitForPodsReady: {enable: true, timeout: 5m}\n"},
+					Op:        OpCmpEqual,
+					Format:    FormatYAML,
+				},
+			},
+			actual: map[string]string{"config.yaml": "waitForPodsReady:\n  timeout: 5m\n  enable: true\nkind: Configuration\n"},
+		},
+		{
+			name: "Case 7: YAML document mismatch",
+			task: &CheckConfigmapTask{
+				BaseTask: BaseTask{taskType: TaskCheckConfigmap, taskID: taskID},
+				checkConfigmapTaskParams: checkConfigmapTaskParams{
+					Name:      "test",
+					Namespace: "default",
+					Data:      map[string]string{"config.yaml": "kind: Configuration\n"},
+					Op:        OpCmpEqual,
+					Format:    FormatYAML,
+				},
+			},
+			actual: map[string]string{"config.yaml": "kind: Configuration\nnamespace: kueue-system\n"},
+			err:    `CheckConfigmap/check: configmap default/test does not match value for key "config.yaml"`,
+		},
+		{
+			name: "Case 8: valid OpCmpSubset case with JSON format",
+			task: &CheckConfigmapTask{
+				BaseTask: BaseTask{taskType: TaskCheckConfigmap, taskID: taskID},
+				checkConfigmapTaskParams: checkConfigmapTaskParams{
+					Name:      "test",
+					Namespace: "default",
+					Data:      map[string]string{"queues.json": `{"partitions": {"default": {"limit": 10}}}`},
+					Op:        OpCmpSubset,
+					Format:    FormatJSON,
+				},
+			},
+			actual: map[string]string{"queues.json": `{"partitions":{"default":{"preemption":true,"limit":10}},"placementRules":[]}`},
+		},
+		{
+			name: "Case 9: invalid actual document",
+			task: &CheckConfigmapTask{
+				BaseTask: BaseTask{taskType: TaskCheckConfigmap, taskID: taskID},
+				checkConfigmapTaskParams: checkConfigmapTaskParams{
+					Name:      "test",
+					Namespace: "default",
+					Data:      map[string]string{"queues.json": `{"limit": 10}`},
+					Op:        OpCmpSubset,
+					Format:    FormatJSON,
+				},
+			},
+			actual: map[string]string{"queues.json": "limit: 10"},
+			err:    `CheckConfigmap/check: configmap default/test: failed to parse value for key "queues.json": invalid character 'l' looking for beginning of value`,
+		},
+		{
+			name: "Case 10: list element mismatch in OpCmpSubset case",
+			task: &CheckConfigmapTask{
+				BaseTask: BaseTask{taskType: TaskCheckConfigmap, taskID: taskID},
+				checkConfigmapTaskParams: checkConfigmapTaskParams{
+					Name:      "test",
+					Namespace: "default",
+					Data:      map[string]string{"config.yaml": "profiles:\n- schedulerName: default\n  plugins: {score: {enabled: [{name: NodeResourcesFit}]}}\n"},
+					Op:        OpCmpSubset,
+					Format:    FormatYAML,
+				},
+			},
+			actual: map[string]string{"config.yaml": "profiles:\n- schedulerName: default\n  plugins: {score: {enabled: [{name: ImageLocality}]}}\n"},
+			err:    `CheckConfigmap/check: configmap default/test does not match value for key "config.yaml"`,
+		},
+		{
+			name: "Case 11: list elements as subsets in OpCmpSubset case",
+			task: &CheckConfigmapTask{
+				BaseTask: BaseTask{taskType: TaskCheckConfigmap, taskID: taskID},
+				checkConfigmapTaskParams: checkConfigmapTaskParams{
+					Name:      "test",
+					Namespace: "default",
+					Data:      map[string]string{"config.yaml": "profiles:\n- schedulerName: default\n"},
+					Op:        OpCmpSubset,
+					Format:    FormatYAML,
+				},
+			},
+			actual: map[string]string{"config.yaml": "profiles:\n- schedulerName: default\n  percentageOfNodesToScore: 50\n"},
+		},
 	}
 
 	for _, tc := range testCases {
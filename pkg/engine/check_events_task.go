@@ -0,0 +1,446 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	log "k8s.io/klog/v2"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+)
+
+// CheckEventsTask represents a task that checks Kubernetes events
+// for the objects submitted by a SubmitObj task, and optionally for their pods.
+type CheckEventsTask struct {
+	BaseTask
+	checkEventsTaskParams
+
+	client    *kubernetes.Clientset
+	dynClient *dynamic.DynamicClient
+	accessor  ObjInfoAccessor
+}
+
+type checkEventsTaskParams struct {
+	RefTaskID string `yaml:"refTaskId"`
+	// Pods: if true, the events for the pods spawned by the objects are checked as well
+	Pods    bool          `yaml:"pods,omitempty"`
+	Events  []*eventSpec  `yaml:"events"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// eventSpec selects events by reason, type and message regexp, and defines the expected number of occurrences.
+// By default, at least one occurrence is expected. Set "max: 0" to verify that no such event occurred.
+type eventSpec struct {
+	Reason  string `yaml:"reason,omitempty"`
+	Type    string `yaml:"type,omitempty"`
+	Message string `yaml:"message,omitempty"`
+	Min     *int   `yaml:"min,omitempty"`
+	Max     *int   `yaml:"max,omitempty"`
+
+	// derived
+	re *regexp.Regexp
+}
+
+// newCheckEventsTask initializes and returns CheckEventsTask
+func newCheckEventsTask(client *kubernetes.Clientset, dynClient *dynamic.DynamicClient, accessor ObjInfoAccessor, cfg *config.Task) (*CheckEventsTask, error) {
+	if client == nil {
+		return nil, fmt.Errorf("%s/%s: Kubernetes client is not set", cfg.Type, cfg.ID)
+	}
+
+	task := &CheckEventsTask{
+		BaseTask: BaseTask{
+			taskType: cfg.Type,
+			taskID:   cfg.ID,
+		},
+		client:    client,
+		dynClient: dynClient,
+		accessor:  accessor,
+	}
+
+	if err := task.validate(cfg.Params); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// validate initializes and validates parameters for CheckEventsTask
+func (task *CheckEventsTask) validate(params map[string]interface{}) error {
+	data, err := yaml.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
+	}
+	if err = yaml.Unmarshal(data, &task.checkEventsTaskParams); err != nil {
+		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
+	}
+
+	if len(task.RefTaskID) == 0 {
+		return fmt.Errorf("%s: missing parameter 'refTaskId'", task.ID())
+	}
+
+	if len(task.Events) == 0 {
+		return fmt.Errorf("%s: missing parameter 'events'", task.ID())
+	}
+
+	for i, spec := range task.Events {
+		if err = spec.validate(); err != nil {
+			return fmt.Errorf("%s: event %d: %v", task.ID(), i, err)
+		}
+	}
+
+	return nil
+}
+
+// validate validates and initializes eventSpec
+func (spec *eventSpec) validate() error {
+	if len(spec.Reason) == 0 && len(spec.Type) == 0 && len(spec.Message) == 0 {
+		return fmt.Errorf("must specify reason, type and/or message")
+	}
+
+	switch spec.Type {
+	case "", v1.EventTypeNormal, v1.EventTypeWarning:
+		// nop
+	default:
+		return fmt.Errorf("invalid event type %q; supported: %s, %s", spec.Type, v1.EventTypeNormal, v1.EventTypeWarning)
+	}
+
+	if len(spec.Message) != 0 {
+		var err error
+		if spec.re, err = regexp.Compile(spec.Message); err != nil {
+			return fmt.Errorf("failed to compile message regexp %q: %v", spec.Message, err)
+		}
+	}
+
+	if spec.Min == nil {
+		// expect at least one occurrence unless only the upper bound is set
+		lower := 1
+		if spec.Max != nil {
+			lower = 0
+		}
+		spec.Min = &lower
+	}
+
+	if *spec.Min < 0 || (spec.Max != nil && *spec.Max < *spec.Min) {
+		return fmt.Errorf("invalid occurrence bounds")
+	}
+
+	return nil
+}
+
+// isMatch returns true if the event matches the spec
+func (spec *eventSpec) isMatch(event *v1.Event) bool {
+	return (len(spec.Reason) == 0 || spec.Reason == event.Reason) &&
+		(len(spec.Type) == 0 || spec.Type == event.Type) &&
+		(spec.re == nil || spec.re.MatchString(event.Message))
+}
+
+// String implements Stringer interface
+func (spec *eventSpec) String() string {
+	parts := []string{}
+	if len(spec.Reason) != 0 {
+		parts = append(parts, "reason="+spec.Reason)
+	}
+	if len(spec.Type) != 0 {
+		parts = append(parts, "type="+spec.Type)
+	}
+	if len(spec.Message) != 0 {
+		parts = append(parts, fmt.Sprintf("message=~%q", spec.Message))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Exec implements Runnable interface
+func (task *CheckEventsTask) Exec(ctx context.Context) error {
+	info, err := task.accessor.GetObjInfo(task.RefTaskID)
+	if err != nil {
+		return err
+	}
+
+	isMatch, err := task.objectMatcher(ctx, info)
+	if err != nil {
+		return err
+	}
+	namespace := eventNamespace(info)
+
+	ec := &eventCounter{
+		events:  make(map[types.UID]*v1.Event),
+		isMatch: isMatch,
+	}
+
+	if task.Timeout == 0 {
+		list, err := task.client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("%s: failed to list events: %v", task.ID(), err)
+		}
+		for i := range list.Items {
+			ec.add(&list.Items[i])
+		}
+		_, err = task.evaluate(ec, true)
+		return err
+	}
+
+	return task.watchEvents(ctx, namespace, ec)
+}
+
+// watchEvents watches the events until the expectations are met, or until the timeout
+func (task *CheckEventsTask) watchEvents(ctx context.Context, namespace string, ec *eventCounter) error {
+	log.Infof("Create event informer with %s timeout", task.Timeout.String())
+
+	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	errs := make(chan error, 1)
+
+	factory := informers.NewSharedInformerFactoryWithOptions(task.client, 0, informers.WithNamespace(namespace))
+	defer factory.Shutdown()
+
+	informer := factory.Core().V1().Events().Informer()
+
+	observe := func(obj interface{}) {
+		event, ok := obj.(*v1.Event)
+		if !ok || !ec.add(event) {
+			return
+		}
+		if done, err := task.evaluate(ec, false); done {
+			select {
+			case errs <- err:
+			default:
+			}
+		}
+	}
+
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: observe,
+		UpdateFunc: func(_, obj interface{}) {
+			observe(obj)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	go informer.Run(ctx.Done())
+
+	select {
+	case <-ctx.Done():
+		_, err = task.evaluate(ec, true)
+		return err
+	case err = <-errs:
+		return err
+	}
+}
+
+// evaluate compares the number of occurrences of the events with the expectations.
+// It returns true when the result is final, i.e., an upper bound is exceeded,
+// or all lower bounds are met and there are no upper bounds to observe until the timeout.
+func (task *CheckEventsTask) evaluate(ec *eventCounter, final bool) (bool, error) {
+	counts := ec.count(task.Events)
+
+	pending := []string{}
+	bounded := false
+	for i, spec := range task.Events {
+		n := counts[i]
+		if spec.Max != nil {
+			bounded = true
+			if n > *spec.Max {
+				return true, fmt.Errorf("%s: event %q occurred %d times, expected at most %d", task.ID(), spec, n, *spec.Max)
+			}
+		}
+		if n < *spec.Min {
+			pending = append(pending, fmt.Sprintf("%q occurred %d times, expected at least %d", spec, n, *spec.Min))
+		}
+	}
+
+	if len(pending) != 0 {
+		if final {
+			return true, fmt.Errorf("%s: events %s", task.ID(), strings.Join(pending, "; "))
+		}
+		return false, nil
+	}
+
+	if bounded && !final {
+		return false, nil
+	}
+
+	log.Infof("Validation passed for events: %v", counts)
+	return true, nil
+}
+
+// eventNamespace returns the namespace of the events: the namespace of the objects,
+// or all namespaces if the objects span namespaces or include cluster-scoped objects
+func eventNamespace(info *ObjInfo) string {
+	for j := range info.GVR {
+		if info.WatchNamespace(j) != info.Namespace {
+			return metav1.NamespaceAll
+		}
+	}
+	return info.Namespace
+}
+
+// objectMatcher returns a function that checks if the event refers to the referenced objects or their pods.
+// The objects are matched by kind, namespace and name, and the pods according to the pod tracking mode of the objects.
+func (task *CheckEventsTask) objectMatcher(ctx context.Context, info *ObjInfo) (func(*v1.ObjectReference) bool, error) {
+	// objs maps the namespace/name of the objects to their resources
+	objs := make(map[string][]schema.GroupResource)
+	for j, gvr := range info.GVR {
+		for i, name := range info.Names {
+			key := objectKey(info.ObjNamespace(j, i), name)
+			objs[key] = append(objs[key], gvr.GroupResource())
+		}
+	}
+
+	var isPod func(*v1.ObjectReference) bool
+	if task.Pods {
+		pods, err := newRefPodSet(ctx, task.dynClient, task.accessor, info)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", task.ID(), err)
+		}
+		isPod = task.podMatcher(ctx, pods)
+	}
+
+	var mutex sync.Mutex
+	// resources caches the resources of the involved object kinds
+	resources := make(map[schema.GroupVersionKind]schema.GroupResource)
+
+	return func(ref *v1.ObjectReference) bool {
+		if candidates, ok := objs[objectKey(ref.Namespace, ref.Name)]; ok {
+			gvk := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind)
+
+			mutex.Lock()
+			gr, ok := resources[gvk]
+			if !ok {
+				if gvr, _, err := task.accessor.GetGVR(gvk); err == nil {
+					gr = gvr.GroupResource()
+				} else {
+					log.V(4).Infof("%s: failed to resolve kind of event object %s: %v", task.ID(), ref.Name, err)
+				}
+				resources[gvk] = gr
+			}
+			mutex.Unlock()
+
+			for _, candidate := range candidates {
+				if candidate == gr {
+					return true
+				}
+			}
+		}
+
+		return isPod != nil && ref.Kind == "Pod" && isPod(ref)
+	}, nil
+}
+
+// podMatcher returns a function that checks if the event refers to a pod of the pod set.
+// Unless the pods are matched by name, the pod is fetched to check its owners and labels.
+func (task *CheckEventsTask) podMatcher(ctx context.Context, pods *podSet) func(*v1.ObjectReference) bool {
+	var mutex sync.Mutex
+	// matched caches the verdict for the pods, keyed by UID
+	matched := make(map[types.UID]bool)
+
+	return func(ref *v1.ObjectReference) bool {
+		if len(pods.namespace) != 0 && ref.Namespace != pods.namespace {
+			return false
+		}
+
+		if pods.byName {
+			return pods.isMatch(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: ref.Name, Namespace: ref.Namespace}})
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if ok, found := matched[ref.UID]; found {
+			return ok
+		}
+
+		pod, err := task.client.CoreV1().Pods(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			// the pod could have been deleted; don't cache the verdict
+			log.V(4).Infof("%s: failed to get pod %s of event: %v", task.ID(), ref.Name, err)
+			return false
+		}
+		if len(ref.UID) != 0 && pod.UID != ref.UID {
+			// the event refers to a previous pod with the same name
+			return false
+		}
+
+		ok := pods.isMatch(pod)
+		if len(ref.UID) != 0 {
+			matched[ref.UID] = ok
+		}
+		return ok
+	}
+}
+
+// eventCounter accumulates the events related to the checked objects
+type eventCounter struct {
+	mutex   sync.Mutex
+	events  map[types.UID]*v1.Event
+	isMatch func(*v1.ObjectReference) bool
+}
+
+// add stores the event if it refers to the checked objects, and returns true if the event was stored
+func (ec *eventCounter) add(event *v1.Event) bool {
+	if !ec.isMatch(&event.InvolvedObject) {
+		return false
+	}
+
+	log.V(4).Infof("Event %s %s for %s %s: %s", event.Type, event.Reason,
+		event.InvolvedObject.Kind, event.InvolvedObject.Name, event.Message)
+
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+	ec.events[event.UID] = event
+
+	return true
+}
+
+// count returns the number of occurrences of the events matching each spec
+func (ec *eventCounter) count(specs []*eventSpec) []int {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+
+	counts := make([]int, len(specs))
+	for _, event := range ec.events {
+		// an event may represent repeated occurrences
+		n := int(event.Count)
+		if n == 0 {
+			n = 1
+		}
+		for i, spec := range specs {
+			if spec.isMatch(event) {
+				counts[i] += n
+			}
+		}
+	}
+
+	return counts
+}
@@ -0,0 +1,300 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+)
+
+func TestCheckEventsParams(t *testing.T) {
+	taskID := "check"
+	one, zero := 1, 0
+	testCases := []struct {
+		name       string
+		simClients bool
+		params     map[string]interface{}
+		refTaskId  string
+		err        string
+		task       *CheckEventsTask
+	}{
+		{
+			name:   "Case 1: no k8s client",
+			params: nil,
+			err:    "CheckEvents/check: Kubernetes client is not set",
+		},
+		{
+			name:       "Case 2: missing task reference ID",
+			simClients: true,
+			params:     map[string]interface{}{},
+			err:        "CheckEvents/check: missing parameter 'refTaskId'",
+		},
+		{
+			name:       "Case 3: missing events",
+			simClients: true,
+			params: map[string]interface{}{
+				"refTaskId": "job",
+			},
+			err: "CheckEvents/check: missing parameter 'events'",
+		},
+		{
+			name:       "Case 4a: empty event spec",
+			simClients: true,
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"events":    []interface{}{map[string]interface{}{"max": 1}},
+			},
+			err: "CheckEvents/check: event 0: must specify reason, type and/or message",
+		},
+		{
+			name:       "Case 4b: invalid event type",
+			simClients: true,
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"events":    []interface{}{map[string]interface{}{"type": "Error"}},
+			},
+			err: "CheckEvents/check: event 0: invalid event type \"Error\"; supported: Normal, Warning",
+		},
+		{
+			name:       "Case 4c: invalid message regexp",
+			simClients: true,
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"events":    []interface{}{map[string]interface{}{"message": "(Insufficient"}},
+			},
+			err: "CheckEvents/check: event 0: failed to compile message regexp \"(Insufficient\": error parsing regexp: missing closing ): `(Insufficient`",
+		},
+		{
+			name:       "Case 4d: invalid bounds",
+			simClients: true,
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"events":    []interface{}{map[string]interface{}{"reason": "Preempted", "min": 2, "max": 1}},
+			},
+			err: "CheckEvents/check: event 0: invalid occurrence bounds",
+		},
+		{
+			name:       "Case 5: unreferenced task",
+			simClients: true,
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"events":    []interface{}{map[string]interface{}{"reason": "Preempted"}},
+			},
+			err: "CheckEvents/check: unreferenced task ID job",
+		},
+		{
+			name:       "Case 6: valid parameters",
+			simClients: true,
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"pods":      true,
+				"events": []interface{}{
+					map[string]interface{}{"reason": "FailedScheduling", "type": "Warning", "message": "Insufficient"},
+					map[string]interface{}{"reason": "Preempted", "max": 0},
+				},
+				"timeout": "1m",
+			},
+			refTaskId: "job",
+			task: &CheckEventsTask{
+				BaseTask: BaseTask{
+					taskType: TaskCheckEvents,
+					taskID:   taskID,
+				},
+				checkEventsTaskParams: checkEventsTaskParams{
+					RefTaskID: "job",
+					Pods:      true,
+					Events: []*eventSpec{
+						{Reason: "FailedScheduling", Type: "Warning", Message: "Insufficient", Min: &one, re: regexp.MustCompile("Insufficient")},
+						{Reason: "Preempted", Min: &zero, Max: &zero},
+					},
+					Timeout: time.Minute,
+				},
+				client:    testK8sClient,
+				dynClient: testDynamicClient,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			eng, err := New(nil, nil, tc.simClients)
+			require.NoError(t, err)
+			if len(tc.refTaskId) != 0 {
+				eng.objInfoMap[tc.refTaskId] = nil
+			}
+			task, err := eng.GetTask(&config.Task{
+				ID:     taskID,
+				Type:   TaskCheckEvents,
+				Params: tc.params,
+			})
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+				require.Nil(t, tc.task)
+			} else {
+				tc.task.accessor = eng
+				require.NoError(t, err)
+				require.NotNil(t, tc.task)
+				require.Equal(t, tc.task, task)
+			}
+		})
+	}
+}
+
+func TestCheckEventsEvaluate(t *testing.T) {
+	eng, err := New(nil, nil, true)
+	require.NoError(t, err)
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	eng.restMapper = mapper
+
+	info := NewObjInfo([]string{"job1"}, "default", []schema.GroupVersionResource{{Group: "batch", Version: "v1", Resource: "jobs"}}, 2, "^job1-[0-9]+-.+$")
+
+	apiVersions := map[string]string{"Pod": "v1", "ConfigMap": "v1", "Job": "batch/v1"}
+	newEvent := func(uid, kind, name, reason, msg string, count int32) *v1.Event {
+		return &v1.Event{
+			ObjectMeta:     metav1.ObjectMeta{UID: types.UID(uid)},
+			InvolvedObject: v1.ObjectReference{APIVersion: apiVersions[kind], Kind: kind, Namespace: "default", Name: name},
+			Reason:         reason,
+			Type:           v1.EventTypeWarning,
+			Message:        msg,
+			Count:          count,
+		}
+	}
+
+	events := []*v1.Event{
+		newEvent("1", "Pod", "job1-0-abc", "FailedScheduling", "0/2 nodes are available: 2 Insufficient cpu.", 3),
+		newEvent("2", "Pod", "job1-1-def", "FailedScheduling", "0/2 nodes are available: 2 Insufficient cpu.", 0),
+		newEvent("3", "Job", "job1", "Suspended", "Job suspended", 1),
+		newEvent("4", "Pod", "job2-0-abc", "Preempted", "Preempted by job3", 1),
+		// objects of another kind or in another namespace with the same name
+		newEvent("5", "ConfigMap", "job1", "Suspended", "Job suspended", 1),
+		newEvent("6", "Job", "job1", "Suspended", "Job suspended", 1),
+	}
+	events[5].InvolvedObject.Namespace = "other"
+
+	testCases := []struct {
+		name   string
+		params map[string]interface{}
+		final  bool
+		done   bool
+		err    string
+	}{
+		{
+			name: "Case 1: presence and absence",
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"pods":      true,
+				"events": []interface{}{
+					map[string]interface{}{"reason": "FailedScheduling", "message": "Insufficient cpu", "min": 4},
+					map[string]interface{}{"reason": "Preempted", "max": 0},
+				},
+			},
+			final: true,
+			done:  true,
+		},
+		{
+			name: "Case 2: wait for absence until timeout",
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"events": []interface{}{
+					map[string]interface{}{"reason": "Preempted", "max": 0},
+				},
+			},
+		},
+		{
+			name: "Case 3: upper bound exceeded",
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"pods":      true,
+				"events": []interface{}{
+					map[string]interface{}{"reason": "FailedScheduling", "max": 2},
+				},
+			},
+			done: true,
+			err:  "CheckEvents/check: event \"reason=FailedScheduling\" occurred 4 times, expected at most 2",
+		},
+		{
+			name: "Case 4: pod events are not checked",
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"events": []interface{}{
+					map[string]interface{}{"reason": "FailedScheduling"},
+					map[string]interface{}{"type": "Warning", "message": "suspended"},
+				},
+			},
+			final: true,
+			done:  true,
+			err:   "CheckEvents/check: events \"reason=FailedScheduling\" occurred 0 times, expected at least 1",
+		},
+		{
+			name: "Case 5: events of other objects with the same name are not checked",
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"events": []interface{}{
+					map[string]interface{}{"reason": "Suspended", "min": 1, "max": 1},
+				},
+			},
+			final: true,
+			done:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &CheckEventsTask{
+				BaseTask: BaseTask{
+					taskType: TaskCheckEvents,
+					taskID:   "check",
+				},
+				accessor: eng,
+			}
+			require.NoError(t, task.validate(tc.params))
+
+			isMatch, err := task.objectMatcher(context.Background(), info)
+			require.NoError(t, err)
+
+			ec := &eventCounter{
+				events:  make(map[types.UID]*v1.Event),
+				isMatch: isMatch,
+			}
+			for _, event := range events {
+				ec.add(event)
+			}
+
+			done, err := task.evaluate(ec, tc.final)
+			require.Equal(t, tc.done, done)
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -18,7 +18,9 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -31,6 +33,9 @@ import (
 	"github.com/NVIDIA/knavigator/pkg/utils"
 )
 
+// targetPollInterval is the interval between the checks of the objects selected by the target selectors
+const targetPollInterval = time.Second
+
 // CheckObjTask represents a task that checks object state and status.
 type CheckObjTask struct {
 	ObjStateTask
@@ -62,42 +67,50 @@ func newCheckObjTask(client *dynamic.DynamicClient, accessor ObjInfoAccessor, cf
 
 // Exec implements Runnable interface
 func (task *CheckObjTask) Exec(ctx context.Context) error {
-	info, err := task.accessor.GetObjInfo(task.RefTaskID)
+	if task.Timeout != 0 && task.Target != nil && !task.Target.isNameOnly() {
+		return task.pollTarget(ctx)
+	}
+
+	info, err := task.getObjInfo(ctx)
 	if err != nil {
 		return err
 	}
 
-	nameMap := utils.NewSyncMap()
+	names := make(map[string]bool)
 	for _, name := range info.Names {
-		nameMap.Set(name, true)
+		names[name] = true
 	}
 
+	// satisfied contains names of the objects that satisfy the predicate
+	satisfied := utils.NewSyncMap()
+	// seen contains names of the objects observed by the informer
+	seen := utils.NewSyncMap()
+
 	// Check once and return if timeout is not set
 	if task.Timeout == 0 {
-		return task.checkStates(ctx, info, nameMap)
+		return task.checkStates(ctx, info, satisfied)
 	}
 
 	// Keep checking until timeout
-	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+	watchCtx, cancel := context.WithTimeout(ctx, task.Timeout)
 	defer cancel()
 
 	// TODO: add TweakListOptionsFunc for the CR
-	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(task.client, 0, info.Namespace, nil)
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(task.client, 0, info.WatchNamespace(task.Index), nil)
 	informer := factory.ForResource(info.GVR[task.Index]).Informer()
 
-	done := make(chan struct{})
-	defer close(done)
+	done := make(chan struct{}, 1)
 
 	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			resource := obj.(*unstructured.Unstructured)
 			log.V(4).Infof("Informer added %s %s", info.GVR[task.Index].Resource, resource.GetName())
-			task.checkStateAsync(ctx, resource.GetName(), info, nameMap, done)
+			task.checkStateAsync(resource, info, names, seen, satisfied, done)
 		},
 		UpdateFunc: func(_, obj interface{}) {
 			resource := obj.(*unstructured.Unstructured)
 			log.V(4).Infof("Informer updated %s %s", info.GVR[task.Index].Resource, resource.GetName())
-			task.checkStateAsync(ctx, resource.GetName(), info, nameMap, done)
+			task.checkStateAsync(resource, info, names, seen, satisfied, done)
 		},
 	})
 	if err != nil {
@@ -108,14 +121,17 @@ func (task *CheckObjTask) Exec(ctx context.Context) error {
 	go informer.Run(stopCh)
 
 	// check the objects synchronously, then use informer
-	if err = task.checkStates(ctx, info, nameMap); err != nil {
+	if err = task.checkStates(ctx, info, satisfied); err != nil {
 		log.V(4).Infof("Wait for completion with informers")
 		select {
-		case <-ctx.Done():
-			log.Errorf("Validation failed for %s %v, err: %v", info.GVR[task.Index].Resource, nameMap.Keys(), err)
-			err = ctx.Err()
+		case <-watchCtx.Done():
+			log.Errorf("Validation failed for %s, err: %v", info.GVR[task.Index].Resource, err)
+			// recheck the objects to report their current state
+			if err = task.checkStates(ctx, info, satisfied); err != nil {
+				err = fmt.Errorf("%w: %w", watchCtx.Err(), err)
+			}
 		case <-done:
-			log.Infof("Validation passed for %s", info.GVR[task.Index].Resource)
+			task.logSatisfied(info, satisfied)
 			err = nil
 		}
 	}
@@ -124,43 +140,119 @@ func (task *CheckObjTask) Exec(ctx context.Context) error {
 	return err
 }
 
-func (task *CheckObjTask) checkStates(ctx context.Context, info *ObjInfo, nameMap *utils.SyncMap) error {
-	for _, name := range info.Names {
-		if err := task.checkState(ctx, name, info, nameMap); err != nil {
-			log.V(4).Info(err.Error())
+// pollTarget re-resolves the target and checks the matching objects until the check passes, or until timeout,
+// since the objects matching the selectors can appear during the wait, e.g. when created by controllers
+func (task *CheckObjTask) pollTarget(ctx context.Context) error {
+	pollCtx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	for {
+		done, err := task.checkTarget(pollCtx)
+		if done {
+			return err
+		}
+		log.V(4).Infof("%v", err)
+
+		select {
+		case <-pollCtx.Done():
+			// recheck the objects to report their current state
+			if _, err = task.checkTarget(ctx); err != nil {
+				err = fmt.Errorf("%w: %w", pollCtx.Err(), err)
+			}
+			return err
+		case <-time.After(targetPollInterval):
+		}
+	}
+}
+
+// checkTarget resolves the target and checks the matching objects once.
+// It returns true if the check passed or failed permanently, and false if it may pass later.
+func (task *CheckObjTask) checkTarget(ctx context.Context) (bool, error) {
+	info, err := task.getObjInfo(ctx)
+	if err != nil {
+		return !errors.Is(err, errNoTargetMatch), err
+	}
+
+	err = task.checkStates(ctx, info, utils.NewSyncMap())
+	return err == nil, err
+}
+
+func (task *CheckObjTask) checkStates(ctx context.Context, info *ObjInfo, satisfied *utils.SyncMap) error {
+	gvr := info.GVR[task.Index]
+	// failed contains the reasons why the objects did not satisfy the predicate
+	failed := make(map[string]string)
+	for i, name := range info.Names {
+		cr, err := task.client.Resource(gvr).Namespace(info.ObjNamespace(task.Index, i)).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			log.V(4).Infof("%s: failed to get %s %s: %v", task.ID(), gvr.Resource, name, err)
+			satisfied.Delete(name)
+			failed[name] = err.Error()
+			continue
+		}
+		if err = task.checkState(cr, satisfied); err != nil {
+			log.V(4).Infof("%s: %s %s: %v", task.ID(), gvr.Resource, name, err)
+			failed[name] = err.Error()
 		}
 	}
 
-	if invalid := nameMap.Keys(); len(invalid) != 0 {
-		return fmt.Errorf("%s: failed to validate %s %v", task.ID(), info.GVR[task.Index].Resource, nameMap.Keys())
+	if n := satisfied.Size(); !task.Quantifier.isSatisfied(n, len(info.Names)) {
+		report := &objReport{
+			resource:  gvr.Resource,
+			expected:  len(info.Names),
+			satisfied: n,
+			failed:    failed,
+		}
+		return fmt.Errorf("%s: %d of %d %s satisfied the predicate, expected %s; satisfied: %v\n%s",
+			task.ID(), n, len(info.Names), gvr.Resource, task.Quantifier.String(), satisfied.Keys(), report)
 	}
 
-	log.Infof("Validation passed for %s", info.GVR[task.Index].Resource)
+	task.logSatisfied(info, satisfied)
 	return nil
 }
 
-func (task *CheckObjTask) checkStateAsync(ctx context.Context, name string, info *ObjInfo, nameMap *utils.SyncMap, done chan struct{}) {
-	if err := task.checkState(ctx, name, info, nameMap); err != nil {
-		log.V(4).Info(err.Error())
+// checkStateAsync updates the map of the objects that satisfy the predicate, and signals if the quantifier is satisfied.
+// Unless the quantifier is monotone, it is evaluated only after all objects have been observed.
+func (task *CheckObjTask) checkStateAsync(cr *unstructured.Unstructured, info *ObjInfo, names map[string]bool,
+	seen, satisfied *utils.SyncMap, done chan struct{}) {
+	if !names[cr.GetName()] {
 		return
 	}
+	complete := seen.Set(cr.GetName(), true) >= len(names) || task.Quantifier.isMonotone()
 
-	if nameMap.Size() == 0 {
-		done <- struct{}{}
+	if err := task.checkState(cr, satisfied); err != nil {
+		log.V(4).Infof("%s: %s %s: %v", task.ID(), info.GVR[task.Index].Resource, cr.GetName(), err)
 	}
-}
 
-// checkState validates state conformance and removes object name from the map if succeeded
-func (task *CheckObjTask) checkState(ctx context.Context, name string, info *ObjInfo, nameMap *utils.SyncMap) error {
-	gvr := info.GVR[task.Index]
-	cr, err := task.client.Resource(gvr).Namespace(info.Namespace).Get(ctx, name, metav1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("%s: failed to get %s %s: %v", task.ID(), gvr.Resource, name, err)
+	if complete && task.Quantifier.isSatisfied(satisfied.Size(), len(info.Names)) {
+		select {
+		case done <- struct{}{}:
+		default:
+		}
 	}
+}
+
+// checkState validates state conformance and updates the map of the objects that satisfy the predicate.
+// The returned error describes the reason of the mismatch.
+func (task *CheckObjTask) checkState(cr *unstructured.Unstructured, satisfied *utils.SyncMap) error {
+	name := cr.GetName()
+
 	if !utils.IsSubset(cr.Object, task.State) {
-		return fmt.Errorf("%s: state mismatch in %s %s", task.ID(), gvr.Resource, name)
+		satisfied.Delete(name)
+		return fmt.Errorf("state mismatch")
+	}
+	for _, a := range task.Assert {
+		if err := a.Check(cr.Object); err != nil {
+			satisfied.Delete(name)
+			return err
+		}
 	}
 
-	nameMap.Delete(name)
+	satisfied.Set(name, true)
 	return nil
 }
+
+// logSatisfied reports the objects that satisfied the predicate
+func (task *CheckObjTask) logSatisfied(info *ObjInfo, satisfied *utils.SyncMap) {
+	log.Infof("Validation passed for %s: %d of %d objects satisfied the predicate (%s): %v",
+		info.GVR[task.Index].Resource, satisfied.Size(), len(info.Names), task.Quantifier.String(), satisfied.Keys())
+}
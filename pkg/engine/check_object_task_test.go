@@ -21,8 +21,11 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/NVIDIA/knavigator/pkg/config"
+	"github.com/NVIDIA/knavigator/pkg/utils"
 )
 
 func TestNewCheckObjTask(t *testing.T) {
@@ -82,6 +85,90 @@ func TestNewCheckObjTask(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Case 5: both task reference and target",
+			params: map[string]interface{}{
+				"refTaskId": 1,
+				"target": map[string]interface{}{
+					"apiVersion":    "kueue.x-k8s.io/v1beta1",
+					"kind":          "Workload",
+					"namespace":     "default",
+					"labelSelector": "app=test",
+				},
+				"state": map[string]interface{}{"a": "b"},
+			},
+			simClients: true,
+			err:        "CheckObj/check: parameters 'refTaskId' and 'target' are mutually exclusive",
+		},
+		{
+			name: "Case 6: invalid quantifier",
+			params: map[string]interface{}{
+				"refTaskId":  1,
+				"state":      map[string]interface{}{"a": "b"},
+				"quantifier": map[string]interface{}{"type": "atLeast", "value": "half"},
+			},
+			simClients: true,
+			err:        "CheckObj/check: invalid value \"half\" in quantifier \"atLeast\"",
+		},
+		{
+			name: "Case 7: valid quantifier",
+			params: map[string]interface{}{
+				"refTaskId":  1,
+				"state":      map[string]interface{}{"a": "b"},
+				"quantifier": map[string]interface{}{"type": "atLeast", "value": "80%"},
+				"timeout":    "1m",
+			},
+			simClients: true,
+			refTaskId:  "1",
+			task: &CheckObjTask{
+				ObjStateTask: ObjStateTask{
+					BaseTask: BaseTask{
+						taskType: TaskCheckObj,
+						taskID:   taskID,
+					},
+					StateParams: StateParams{
+						RefTaskID:  "1",
+						State:      map[string]interface{}{"a": "b"},
+						Quantifier: &Quantifier{Type: QuantifierAtLeast, Value: "80%", percent: 80},
+						Timeout:    time.Minute,
+					},
+					client: testDynamicClient,
+				},
+			},
+		},
+		{
+			name: "Case 8: valid target",
+			params: map[string]interface{}{
+				"target": map[string]interface{}{
+					"apiVersion":    "kueue.x-k8s.io/v1beta1",
+					"kind":          "Workload",
+					"namespace":     "default",
+					"labelSelector": "app=test",
+				},
+				"state":   map[string]interface{}{"a": "b"},
+				"timeout": "5s",
+			},
+			simClients: true,
+			task: &CheckObjTask{
+				ObjStateTask: ObjStateTask{
+					BaseTask: BaseTask{
+						taskType: TaskCheckObj,
+						taskID:   taskID,
+					},
+					StateParams: StateParams{
+						Target: &ObjTarget{
+							APIVersion:    "kueue.x-k8s.io/v1beta1",
+							Kind:          "Workload",
+							Namespace:     "default",
+							LabelSelector: "app=test",
+						},
+						State:   map[string]interface{}{"a": "b"},
+						Timeout: 5 * time.Second,
+					},
+					client: testDynamicClient,
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -109,3 +196,74 @@ func TestNewCheckObjTask(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckStateAsync(t *testing.T) {
+	testCases := []struct {
+		name       string
+		quantifier *Quantifier
+		phases     []string
+		passAt     int
+	}{
+		{
+			name:       "Case 1: atMost passes after all objects are observed",
+			quantifier: &Quantifier{Type: QuantifierAtMost, Value: "1"},
+			phases:     []string{"Pending", "Pending", "Pending"},
+			passAt:     2,
+		},
+		{
+			name:       "Case 2: none fails on a later object",
+			quantifier: &Quantifier{Type: QuantifierNone},
+			phases:     []string{"Pending", "Done", "Pending"},
+			passAt:     -1,
+		},
+		{
+			name:       "Case 3: exactly fails on a later object",
+			quantifier: &Quantifier{Type: QuantifierExactly, Value: "1"},
+			phases:     []string{"Done", "Pending", "Done"},
+			passAt:     -1,
+		},
+		{
+			name:       "Case 4: any passes early",
+			quantifier: &Quantifier{Type: QuantifierAny},
+			phases:     []string{"Done", "Pending", "Pending"},
+			passAt:     0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.quantifier.validate())
+			task := &CheckObjTask{
+				ObjStateTask: ObjStateTask{
+					BaseTask: BaseTask{taskType: TaskCheckObj, taskID: "check"},
+					StateParams: StateParams{
+						State:      map[string]interface{}{"status": map[string]interface{}{"phase": "Done"}},
+						Quantifier: tc.quantifier,
+					},
+				},
+			}
+			names := []string{"obj0", "obj1", "obj2"}
+			info := NewObjInfo(names, "default", []schema.GroupVersionResource{{Group: "example.com", Version: "v1", Resource: "jobs"}}, 0)
+			nameMap := map[string]bool{"obj0": true, "obj1": true, "obj2": true}
+			seen, satisfied := utils.NewSyncMap(), utils.NewSyncMap()
+			done := make(chan struct{}, 1)
+
+			passAt := -1
+			for i, phase := range tc.phases {
+				cr := &unstructured.Unstructured{Object: map[string]interface{}{
+					"metadata": map[string]interface{}{"name": names[i]},
+					"status":   map[string]interface{}{"phase": phase},
+				}}
+				task.checkStateAsync(cr, info, nameMap, seen, satisfied, done)
+				select {
+				case <-done:
+					if passAt < 0 {
+						passAt = i
+					}
+				default:
+				}
+			}
+			require.Equal(t, tc.passAt, passAt)
+		})
+	}
+}
@@ -19,12 +19,16 @@ package engine
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -42,19 +46,26 @@ type CheckPodTask struct {
 	BaseTask
 	checkPodTaskParams
 
-	client   *kubernetes.Clientset
-	accessor ObjInfoAccessor
+	client    *kubernetes.Clientset
+	dynClient *dynamic.DynamicClient
+	accessor  ObjInfoAccessor
 }
 
 type checkPodTaskParams struct {
-	RefTaskID  string            `yaml:"refTaskId"`
+	RefTaskID string `yaml:"refTaskId"`
+	// Names: an alternative to RefTaskID, selects pods by name; see utils.NameSelector
+	Names *utils.NameSelector `yaml:"names,omitempty"`
+	// Namespace: namespace of the pods selected by Names
+	Namespace  string            `yaml:"namespace,omitempty"`
 	Status     string            `yaml:"status"`
 	NodeLabels map[string]string `yaml:"nodeLabels"`
-	Timeout    time.Duration     `yaml:"timeout"`
+	// Quantifier defines how many pods must have the expected status; default is "all"
+	Quantifier *Quantifier   `yaml:"quantifier,omitempty"`
+	Timeout    time.Duration `yaml:"timeout"`
 }
 
 // newCheckPodTask initializes and returns CheckPodTask
-func newCheckPodTask(client *kubernetes.Clientset, accessor ObjInfoAccessor, cfg *config.Task) (*CheckPodTask, error) {
+func newCheckPodTask(client *kubernetes.Clientset, dynClient *dynamic.DynamicClient, accessor ObjInfoAccessor, cfg *config.Task) (*CheckPodTask, error) {
 	if client == nil {
 		return nil, fmt.Errorf("%s/%s: Kubernetes client is not set", cfg.Type, cfg.ID)
 	}
@@ -64,8 +75,9 @@ func newCheckPodTask(client *kubernetes.Clientset, accessor ObjInfoAccessor, cfg
 			taskType: cfg.Type,
 			taskID:   cfg.ID,
 		},
-		client:   client,
-		accessor: accessor,
+		client:    client,
+		dynClient: dynClient,
+		accessor:  accessor,
 	}
 
 	if err := task.validate(cfg.Params); err != nil {
@@ -85,7 +97,21 @@ func (task *CheckPodTask) validate(params map[string]interface{}) error {
 		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
 	}
 
-	if len(task.RefTaskID) == 0 {
+	if task.Names != nil {
+		if len(task.RefTaskID) != 0 {
+			return fmt.Errorf("%s: parameters 'refTaskId' and 'names' are mutually exclusive", task.ID())
+		}
+		if len(task.Namespace) == 0 {
+			return fmt.Errorf("%s: missing parameter 'namespace'", task.ID())
+		}
+		if len(task.Names.Regexp) != 0 {
+			return fmt.Errorf("%s: pod names must be specified as a list or a range", task.ID())
+		}
+		task.Names.Init()
+		if err = task.Names.Finalize(); err != nil {
+			return fmt.Errorf("%s: invalid pod names: %v", task.ID(), err)
+		}
+	} else if len(task.RefTaskID) == 0 {
 		return fmt.Errorf("%s: missing parameter 'refTaskId'", task.ID())
 	}
 
@@ -93,121 +119,425 @@ func (task *CheckPodTask) validate(params map[string]interface{}) error {
 		return fmt.Errorf("%s: missing parameters 'status' and/or 'nodeLabels'", task.ID())
 	}
 
+	if task.Quantifier != nil {
+		if err = task.Quantifier.validate(); err != nil {
+			return fmt.Errorf("%s: %v", task.ID(), err)
+		}
+	}
+
 	return nil
 }
 
 // Exec implements Runnable interface
 func (task *CheckPodTask) Exec(ctx context.Context) error {
-	info, err := task.accessor.GetObjInfo(task.RefTaskID)
+	pods, err := task.getPodSet(ctx)
 	if err != nil {
 		return err
 	}
 
-	if len(info.PodRegexp) == 0 {
-		return fmt.Errorf("%s: no pods to check", task.ID())
+	// with unknown pod count, the check passes only once the pod set is stable
+	if pods.countUnknown && !task.Quantifier.isMonotone() && task.Timeout == 0 {
+		return fmt.Errorf("%s: the number of pods is unknown; set 'podCount' in the RegisterObj task, or set 'timeout'", task.ID())
 	}
 
 	if task.Timeout == 0 {
-		return task.checkPods(ctx, info)
+		return task.checkPods(ctx, pods)
 	}
-	return task.watchPods(ctx, info)
+	return task.watchPods(ctx, pods)
 }
 
-func (task *CheckPodTask) checkPods(ctx context.Context, info *ObjInfo) error {
-	list, err := task.client.CoreV1().Pods(info.Namespace).List(ctx, metav1.ListOptions{})
+// podSet describes a set of pods to check
+type podSet struct {
+	namespace string
+	count     int
+	// names: expected pod names, if known
+	names   []string
+	isMatch func(*v1.Pod) bool
+	// byName: isMatch depends on the pod name only
+	byName bool
+	// selector: an optional label selector of the pods
+	selector string
+	// countUnknown: the expected number of pods is unknown, and is taken to be the number of matched pods
+	countUnknown bool
+	// found: names of the matched pods, if the count is unknown
+	found *utils.SyncMap
+}
+
+// total returns the expected number of pods, given the number of matched pods
+func (pods *podSet) total(matched int) int {
+	if pods.countUnknown {
+		return matched
+	}
+	return pods.count
+}
+
+// getPodSet returns pods spawned by the referenced objects, or pods selected by name
+func (task *CheckPodTask) getPodSet(ctx context.Context) (*podSet, error) {
+	if task.Names != nil {
+		matcher := task.Names.Matcher()
+		return &podSet{
+			namespace: task.Namespace,
+			count:     len(task.Names.Names()),
+			names:     task.Names.Names(),
+			isMatch:   func(pod *v1.Pod) bool { return matcher.IsMatch(pod.Name) },
+			byName:    true,
+		}, nil
+	}
+
+	info, err := task.accessor.GetObjInfo(task.RefTaskID)
 	if err != nil {
-		return fmt.Errorf("%s: failed to list pods: %v", task.ID(), err)
+		return nil, err
 	}
 
+	pods, err := newRefPodSet(ctx, task.dynClient, task.accessor, info)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", task.ID(), err)
+	}
+
+	return pods, nil
+}
+
+// newRefPodSet returns pods spawned by the submitted objects, according to their pod tracking mode
+func newRefPodSet(ctx context.Context, client *dynamic.DynamicClient, accessor ObjInfoAccessor, info *ObjInfo) (*podSet, error) {
+	switch info.PodTracking {
+	case PodTrackingOwner:
+		return newOwnerPodSet(ctx, client, accessor, info), nil
+	case PodTrackingLabel:
+		if len(info.Labels) == 0 {
+			return nil, fmt.Errorf("objects have no tracking labels")
+		}
+		return newLabelPodSet(info), nil
+	}
+
+	if len(info.PodRegexp) == 0 {
+		return nil, fmt.Errorf("no pods to check")
+	}
+
+	return newObjPodSet(info)
+}
+
+// numFound returns the number of matched pods tracked by the pod set
+func (pods *podSet) numFound() int {
+	if pods.found == nil {
+		return 0
+	}
+	return pods.found.Size()
+}
+
+// newObjPodSet returns pods spawned by the objects
+func newObjPodSet(info *ObjInfo) (*podSet, error) {
 	re, err := utils.Exp2Regexp(info.PodRegexp)
 	if err != nil {
-		return fmt.Errorf("%s: %v", task.ID(), err)
+		return nil, err
 	}
 
-	var count int
-	for i := range list.Items {
-		pod := &list.Items[i]
-		for _, r := range re {
-			if r.MatchString(pod.Name) {
-				log.V(4).Infof("Matched pod %s", pod.Name)
-				count++
-
-				status := string(pod.Status.Phase)
-				if status != task.Status {
-					return fmt.Errorf("%s: pod %s, status %s, expected %s", task.ID(), pod.Name, status, task.Status)
+	return &podSet{
+		namespace: info.Namespace,
+		count:     info.PodCount,
+		isMatch: func(pod *v1.Pod) bool {
+			for _, r := range re {
+				if r.MatchString(pod.Name) {
+					return true
 				}
+			}
+			return false
+		},
+		byName: true,
+	}, nil
+}
 
-				if err := task.verifyLabels(ctx, pod); err != nil {
-					return err
-				}
+// newOwnerPodSet returns pods owned by the objects
+func newOwnerPodSet(ctx context.Context, client *dynamic.DynamicClient, accessor ObjInfoAccessor, info *ObjInfo) *podSet {
+	tracker := newOwnerTracker(client, accessor, info)
+	return &podSet{
+		namespace:    info.Namespace,
+		count:        info.PodCount,
+		isMatch:      func(pod *v1.Pod) bool { return tracker.isOwned(ctx, pod) },
+		countUnknown: info.PodCount == 0,
+		found:        utils.NewSyncMap(),
+	}
+}
+
+// newLabelPodSet returns pods with the tracking labels of the objects
+func newLabelPodSet(info *ObjInfo) *podSet {
+	selector := labels.SelectorFromSet(info.Labels)
+	return &podSet{
+		namespace:    info.Namespace,
+		count:        info.PodCount,
+		isMatch:      func(pod *v1.Pod) bool { return selector.Matches(labels.Set(pod.Labels)) },
+		selector:     selector.String(),
+		countUnknown: info.PodCount == 0,
+		found:        utils.NewSyncMap(),
+	}
+}
+
+func (task *CheckPodTask) checkPods(ctx context.Context, pods *podSet) error {
+	matched, err := task.listPods(ctx, pods)
+	if err != nil {
+		return err
+	}
+
+	if pods.countUnknown && len(matched) == 0 {
+		return task.failure(ctx, pods, matched, fmt.Errorf("%s: no pods found", task.ID()))
+	}
+	total := pods.total(len(matched))
+
+	satisfied := []string{}
+	for _, pod := range matched {
+		status := string(pod.Status.Phase)
+		if status != task.Status {
+			if task.Quantifier.isAll() {
+				err = fmt.Errorf("%s: pod %s, status %s, expected %s", task.ID(), pod.Name, status, task.Status)
+				return task.failure(ctx, pods, matched, err)
 			}
+			continue
 		}
+
+		if err = task.verifyLabels(ctx, pod); err != nil {
+			// the pod does not satisfy the predicate; the quantifier decides if the check fails
+			if task.Quantifier.isAll() {
+				return task.failure(ctx, pods, matched, err)
+			}
+			log.V(4).Infof("%v", err)
+			continue
+		}
+		satisfied = append(satisfied, pod.Name)
+	}
+
+	if task.Quantifier.isAll() && len(matched) != total {
+		err = fmt.Errorf("%s: verified %d pods, expected %d", task.ID(), len(matched), total)
+		return task.failure(ctx, pods, matched, err)
 	}
 
-	if count != info.PodCount {
-		return fmt.Errorf("%s: verified %d pods, expected %d", task.ID(), count, info.PodCount)
+	if !task.Quantifier.isSatisfied(len(satisfied), total) {
+		err = fmt.Errorf("%s: %d of %d pods satisfied the predicate (%s), expected %s; satisfied: %v",
+			task.ID(), len(satisfied), total, task.predicate(), task.Quantifier.String(), satisfied)
+		return task.failure(ctx, pods, matched, err)
 	}
 
+	log.Infof("Validation passed for pods: %d of %d pods satisfied the predicate (%s): %v",
+		len(satisfied), total, task.Quantifier.String(), satisfied)
+
 	return nil
 }
 
+// listPods returns the pods from the pod set
+func (task *CheckPodTask) listPods(ctx context.Context, pods *podSet) ([]*v1.Pod, error) {
+	list, err := task.client.CoreV1().Pods(pods.namespace).List(ctx, metav1.ListOptions{LabelSelector: pods.selector})
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to list pods: %v", task.ID(), err)
+	}
+
+	matched := []*v1.Pod{}
+	for i := range list.Items {
+		if pod := &list.Items[i]; pods.isMatch(pod) {
+			log.V(4).Infof("Matched pod %s", pod.Name)
+			matched = append(matched, pod)
+		}
+	}
+
+	return matched, nil
+}
+
 // watchPods watches statuses of given pods and compares them with the expected status.
 // The function runs until all statuses are equal to the expected one, or until the timeout, whichever comes first.
-func (task *CheckPodTask) watchPods(ctx context.Context, info *ObjInfo) error {
-	log.Infof("Create pod informer for %d pods with %s timeout", info.PodCount, task.Timeout.String())
-
-	re, err := utils.Exp2Regexp(info.PodRegexp)
-	if err != nil {
-		return fmt.Errorf("%s: %v", task.ID(), err)
+func (task *CheckPodTask) watchPods(ctx context.Context, pods *podSet) error {
+	if pods.countUnknown {
+		log.Infof("Create pod informer for owned pods with %s timeout", task.Timeout.String())
+	} else {
+		log.Infof("Create pod informer for %d pods with %s timeout", pods.count, task.Timeout.String())
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+	watchCtx, cancel := context.WithTimeout(ctx, task.Timeout)
 	defer cancel()
 
 	podMap := utils.NewSyncMap()
+	// seen contains names of all matched pods observed by the informer
+	seen := utils.NewSyncMap()
 
 	errs := make(chan error)
 
-	factory := informers.NewSharedInformerFactoryWithOptions(task.client, 30*time.Second, informers.WithNamespace(info.Namespace))
+	factory := informers.NewSharedInformerFactoryWithOptions(task.client, 30*time.Second, informers.WithNamespace(pods.namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) { opts.LabelSelector = pods.selector }))
 	defer factory.Shutdown()
 
 	informer := factory.Core().V1().Pods().Informer()
 
-	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
-			task.verifyPod(ctx, re, podMap, info.PodCount, obj, errs)
+			task.verifyPod(watchCtx, pods, podMap, seen, obj, errs)
 		},
 		UpdateFunc: func(_, obj interface{}) {
-			task.verifyPod(ctx, re, podMap, info.PodCount, obj, errs)
+			task.verifyPod(watchCtx, pods, podMap, seen, obj, errs)
 		},
 	})
 	if err != nil {
 		return err
 	}
 
-	go informer.Run(ctx.Done())
+	go informer.Run(watchCtx.Done())
 	go func() {
-		list, err := task.client.CoreV1().Pods(info.Namespace).List(ctx, metav1.ListOptions{})
+		list, err := task.client.CoreV1().Pods(pods.namespace).List(watchCtx, metav1.ListOptions{LabelSelector: pods.selector})
 		if err != nil {
-			errs <- fmt.Errorf("%s: failed to list pods: %v", task.ID(), err)
+			sendErr(watchCtx, errs, fmt.Errorf("%s: failed to list pods: %v", task.ID(), err))
 			return
 		}
 		for i := range list.Items {
-			if podMap.Size() == info.PodCount {
-				break
-			}
-			task.verifyPod(ctx, re, podMap, info.PodCount, &list.Items[i], errs)
+			task.verifyPod(watchCtx, pods, podMap, seen, &list.Items[i], errs)
 		}
 	}()
 
+	// with unknown pod count, the quantifiers depending on the total number of pods are satisfied
+	// only if no new pods are found during the settle period, and the pods still satisfy the predicate
+	settleRequired := pods.countUnknown && !task.Quantifier.isMonotone()
+	var settle <-chan time.Time
+	settledFound := 0
+
 	for {
 		select {
-		case <-ctx.Done():
-			return ctx.Err()
+		case <-watchCtx.Done():
+			err = fmt.Errorf("%s: %d of %d pods satisfied the predicate (%s) within %s: %w",
+				task.ID(), podMap.Size(), pods.total(pods.numFound()), task.Quantifier.String(), task.Timeout.String(), watchCtx.Err())
+			return task.failure(ctx, pods, nil, err)
 		case err := <-errs:
+			if err != nil {
+				return task.failure(ctx, pods, nil, err)
+			}
+			if !settleRequired {
+				return nil
+			}
+			if settle == nil || pods.numFound() != settledFound {
+				log.V(4).Infof("Waiting %s for the set of %d pods to settle", podSettlePeriod.String(), pods.numFound())
+				settledFound = pods.numFound()
+				settle = time.After(podSettlePeriod)
+			}
+		case <-settle:
+			settle = nil
+			if pods.numFound() != settledFound {
+				settledFound = pods.numFound()
+				settle = time.After(podSettlePeriod)
+				continue
+			}
+			if err = task.checkPods(watchCtx, pods); err == nil {
+				return nil
+			}
+			log.V(4).Infof("Pods changed during the settle period: %v", err)
+		}
+	}
+}
+
+// failure appends the report on the current state of the pods to the validation error
+func (task *CheckPodTask) failure(ctx context.Context, pods *podSet, matched []*v1.Pod, err error) error {
+	if matched == nil {
+		var lerr error
+		if matched, lerr = task.listPods(ctx, pods); lerr != nil {
+			log.Errorf("Failed to create pod report: %v", lerr)
 			return err
 		}
 	}
+
+	report := newPodReport(pods.total(len(matched)), pods.names, matched)
+	task.reportNodeLabels(ctx, report, matched)
+	task.reportSchedulingEvents(ctx, report, pods.namespace, matched)
+
+	return fmt.Errorf("%w\n%s", err, report)
+}
+
+// reportNodeLabels adds the pods running on the nodes without the expected labels to the report
+func (task *CheckPodTask) reportNodeLabels(ctx context.Context, report *podReport, matched []*v1.Pod) {
+	if len(task.NodeLabels) == 0 {
+		return
+	}
+
+	nodes := make(map[string]*v1.Node)
+	for _, pod := range matched {
+		if len(pod.Spec.NodeName) == 0 {
+			continue
+		}
+		node, ok := nodes[pod.Spec.NodeName]
+		if !ok {
+			var err error
+			if node, err = task.client.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{}); err != nil {
+				log.V(4).Infof("Failed to get node %s: %v", pod.Spec.NodeName, err)
+				node = nil
+			}
+			nodes[pod.Spec.NodeName] = node
+		}
+		if node == nil {
+			continue
+		}
+
+		missing := []string{}
+		for key, val := range task.NodeLabels {
+			if node.Labels[key] != val {
+				missing = append(missing, key+"="+val)
+			}
+		}
+		if len(missing) != 0 {
+			sort.Strings(missing)
+			report.mislabeled = append(report.mislabeled,
+				fmt.Sprintf("%s on node %s: missing %s", pod.Name, node.Name, strings.Join(missing, ", ")))
+		}
+	}
+}
+
+// reportSchedulingEvents adds the latest FailedScheduling event for each pending pod to the report
+func (task *CheckPodTask) reportSchedulingEvents(ctx context.Context, report *podReport, namespace string, matched []*v1.Pod) {
+	pending := make(map[string]bool)
+	for _, pod := range matched {
+		if pod.Status.Phase == v1.PodPending {
+			pending[pod.Name] = true
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	list, err := task.client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.Set{"reason": "FailedScheduling", "involvedObject.kind": "Pod"}.AsSelector().String(),
+	})
+	if err != nil {
+		log.V(4).Infof("Failed to list events: %v", err)
+		return
+	}
+
+	latest := make(map[string]time.Time)
+	for i := range list.Items {
+		event := &list.Items[i]
+		name := event.InvolvedObject.Name
+		if !pending[name] {
+			continue
+		}
+		ts := eventTime(event)
+		if t, ok := latest[name]; !ok || ts.After(t) {
+			latest[name] = ts
+			report.events[name] = event.Message
+		}
+	}
+}
+
+// eventTime returns the time of the last occurrence of the event
+func eventTime(event *v1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// predicate describes the expected pod status and node labels
+func (task *CheckPodTask) predicate() string {
+	parts := []string{}
+	if len(task.Status) != 0 {
+		parts = append(parts, "status "+task.Status)
+	}
+	if len(task.NodeLabels) != 0 {
+		parts = append(parts, "node labels "+labels.SelectorFromSet(task.NodeLabels).String())
+	}
+	return strings.Join(parts, ", ")
 }
 
 func (task *CheckPodTask) verifyLabels(ctx context.Context, pod *v1.Pod) error {
@@ -229,33 +559,63 @@ func (task *CheckPodTask) verifyLabels(ctx context.Context, pod *v1.Pod) error {
 	return nil
 }
 
-func (task *CheckPodTask) verifyPod(ctx context.Context, re []*regexp.Regexp, podMap *utils.SyncMap, count int, obj interface{}, errs chan error) {
+// verifyPod updates the map of the pods that satisfy the predicate, and reports if the quantifier is satisfied.
+// Unless the quantifier is monotone, it is evaluated only after all expected pods have been observed.
+func (task *CheckPodTask) verifyPod(ctx context.Context, pods *podSet, podMap, seen *utils.SyncMap, obj interface{}, errs chan error) {
 	pod, ok := obj.(*v1.Pod)
 	if !ok {
-		errs <- fmt.Errorf("%s: unexpected object type %T, expected *v1.Pod", task.ID(), obj)
+		sendErr(ctx, errs, fmt.Errorf("%s: unexpected object type %T, expected *v1.Pod", task.ID(), obj))
 		return
 	}
 
-	for _, r := range re {
-		if r.MatchString(pod.Name) {
-			log.V(4).Infof("Matched pod %s", pod.Name)
-			if _, ok := podMap.Get(pod.Name); ok {
-				return
-			}
-			status := string(pod.Status.Phase)
-			log.V(4).Infof("Informer event for pod %s with status %s", pod.Name, status)
-			if status != task.Status {
-				return
-			}
-			if err := task.verifyLabels(ctx, pod); err != nil {
-				errs <- err
-				return
-			}
-			if sz := podMap.Set(pod.Name, true); sz == count {
-				log.Infof("Accounted for all pods")
-				errs <- nil
-				return
-			}
+	if !pods.isMatch(pod) {
+		return
+	}
+
+	log.V(4).Infof("Matched pod %s", pod.Name)
+	found := 0
+	if pods.countUnknown {
+		found = pods.found.Set(pod.Name, true)
+	}
+	total := pods.total(found)
+	numSeen := seen.Set(pod.Name, true)
+
+	status := string(pod.Status.Phase)
+	log.V(4).Infof("Informer event for pod %s with status %s", pod.Name, status)
+
+	var sz int
+	if status != task.Status {
+		sz = podMap.Delete(pod.Name)
+	} else {
+		if _, ok := podMap.Get(pod.Name); ok {
+			return
 		}
+		if err := task.verifyLabels(ctx, pod); err != nil {
+			// the pod does not satisfy the predicate; the quantifier decides if the check passes
+			log.V(4).Infof("%v", err)
+			sz = podMap.Delete(pod.Name)
+		} else {
+			sz = podMap.Set(pod.Name, true)
+		}
+	}
+
+	// with unknown pod count, the settle period ensures that all pods have been observed
+	complete := total > 0
+	if !pods.countUnknown {
+		complete = numSeen >= total || task.Quantifier.isMonotone()
+	}
+
+	if complete && task.Quantifier.isSatisfied(sz, total) {
+		log.Infof("Validation passed for pods: %d of %d pods satisfied the predicate (%s): %v",
+			sz, total, task.Quantifier.String(), podMap.Keys())
+		sendErr(ctx, errs, nil)
+	}
+}
+
+// sendErr sends the error to the channel unless the context is done
+func sendErr(ctx context.Context, errs chan error, err error) {
+	select {
+	case errs <- err:
+	case <-ctx.Done():
 	}
 }
@@ -17,12 +17,18 @@
 package engine
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/NVIDIA/knavigator/pkg/config"
+	"github.com/NVIDIA/knavigator/pkg/utils"
 )
 
 func TestCheckPodParams(t *testing.T) {
@@ -32,6 +38,7 @@ func TestCheckPodParams(t *testing.T) {
 		simClients bool
 		params     map[string]interface{}
 		refTaskId  string
+		podNames   string
 		err        string
 		task       *CheckPodTask
 	}{
@@ -94,7 +101,67 @@ func TestCheckPodParams(t *testing.T) {
 					NodeLabels: map[string]string{"l1": "v1", "l2": "v2"},
 					Timeout:    time.Minute,
 				},
-				client: testK8sClient,
+				client:    testK8sClient,
+				dynClient: testDynamicClient,
+			},
+		},
+		{
+			name:       "Case 6: pod names without namespace",
+			simClients: true,
+			params: map[string]interface{}{
+				"names": map[string]interface{}{
+					"list": map[string]interface{}{"patterns": []string{"pod-1", "pod-2"}},
+				},
+				"status": "Running",
+			},
+			err: "CheckPod/check: missing parameter 'namespace'",
+		},
+		{
+			name:       "Case 7: pod names with regexp",
+			simClients: true,
+			params: map[string]interface{}{
+				"names":     map[string]interface{}{"regexp": "^pod-[0-9]+$"},
+				"namespace": "default",
+				"status":    "Running",
+			},
+			err: "CheckPod/check: pod names must be specified as a list or a range",
+		},
+		{
+			name:       "Case 8: invalid quantifier",
+			simClients: true,
+			params: map[string]interface{}{
+				"refTaskId":  "step1",
+				"status":     "Running",
+				"quantifier": map[string]interface{}{"type": "atLeast"},
+			},
+			err: "CheckPod/check: quantifier \"atLeast\" requires value",
+		},
+		{
+			name:       "Case 9: valid parameters with pod names",
+			simClients: true,
+			params: map[string]interface{}{
+				"names": map[string]interface{}{
+					"range": map[string]interface{}{"pattern": "pod-{{._INDEX_}}", "ranges": []string{"1-16", "20"}},
+				},
+				"namespace": "default",
+				"status":    "Running",
+			},
+			podNames: `
+range:
+  pattern: "pod-{{._INDEX_}}"
+  ranges: ["1-16", "20"]
+`,
+			task: &CheckPodTask{
+				BaseTask: BaseTask{
+					taskType: TaskCheckPod,
+					taskID:   taskID,
+				},
+				checkPodTaskParams: checkPodTaskParams{
+					Namespace: "default",
+					Status:    "Running",
+				},
+				client:    testK8sClient,
+				dynClient: testDynamicClient,
 			},
 		},
 	}
@@ -116,6 +183,9 @@ func TestCheckPodParams(t *testing.T) {
 				require.Nil(t, tc.task)
 			} else {
 				tc.task.accessor = eng
+				if len(tc.podNames) != 0 {
+					tc.task.Names = newTestNameSelector(t, tc.podNames)
+				}
 				require.NoError(t, err)
 				require.NotNil(t, tc.task)
 				require.Equal(t, tc.task, task)
@@ -123,3 +193,111 @@ func TestCheckPodParams(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckPodUnknownCount(t *testing.T) {
+	eng, err := New(nil, nil, true)
+	require.NoError(t, err)
+
+	info := NewObjInfo([]string{"job1"}, "default", []schema.GroupVersionResource{{Group: "ray.io", Version: "v1", Resource: "rayjobs"}}, 0)
+	info.PodTracking = PodTrackingLabel
+	info.Labels = map[string]string{LabelRunID: "run", LabelTaskID: "job"}
+	eng.objInfoMap["job"] = info
+
+	task, err := eng.GetTask(&config.Task{
+		ID:     "check",
+		Type:   TaskCheckPod,
+		Params: map[string]interface{}{"refTaskId": "job", "status": "Running"},
+	})
+	require.NoError(t, err)
+	require.EqualError(t, task.Exec(context.Background()),
+		"CheckPod/check: the number of pods is unknown; set 'podCount' in the RegisterObj task, or set 'timeout'")
+}
+
+func TestCheckPodPredicate(t *testing.T) {
+	task := &CheckPodTask{checkPodTaskParams: checkPodTaskParams{Status: "Running"}}
+	require.Equal(t, "status Running", task.predicate())
+
+	task.NodeLabels = map[string]string{"l2": "v2", "l1": "v1"}
+	require.Equal(t, "status Running, node labels l1=v1,l2=v2", task.predicate())
+}
+
+func TestVerifyPodWatch(t *testing.T) {
+	testCases := []struct {
+		name       string
+		quantifier *Quantifier
+		statuses   []string
+		passAt     int
+	}{
+		{
+			name:       "Case 1: atMost passes after all pods are observed",
+			quantifier: &Quantifier{Type: QuantifierAtMost, Value: "1"},
+			statuses:   []string{"Pending", "Pending", "Pending"},
+			passAt:     2,
+		},
+		{
+			name:       "Case 2: none passes after all pods are observed",
+			quantifier: &Quantifier{Type: QuantifierNone},
+			statuses:   []string{"Pending", "Pending", "Pending"},
+			passAt:     2,
+		},
+		{
+			name:       "Case 3: none fails on a later pod",
+			quantifier: &Quantifier{Type: QuantifierNone},
+			statuses:   []string{"Pending", "Running", "Pending"},
+			passAt:     -1,
+		},
+		{
+			name:       "Case 4: exactly fails on a later pod",
+			quantifier: &Quantifier{Type: QuantifierExactly, Value: "1"},
+			statuses:   []string{"Running", "Pending", "Running"},
+			passAt:     -1,
+		},
+		{
+			name:       "Case 5: exactly passes after all pods are observed",
+			quantifier: &Quantifier{Type: QuantifierExactly, Value: "1"},
+			statuses:   []string{"Running", "Pending", "Pending"},
+			passAt:     2,
+		},
+		{
+			name:       "Case 6: atLeast passes early",
+			quantifier: &Quantifier{Type: QuantifierAtLeast, Value: "1"},
+			statuses:   []string{"Running", "Pending", "Pending"},
+			passAt:     0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.quantifier.validate())
+			task := &CheckPodTask{
+				BaseTask:           BaseTask{taskType: TaskCheckPod, taskID: "check"},
+				checkPodTaskParams: checkPodTaskParams{Status: "Running", Quantifier: tc.quantifier},
+			}
+			pods := &podSet{
+				count:   len(tc.statuses),
+				isMatch: func(*v1.Pod) bool { return true },
+				byName:  true,
+			}
+			podMap, seen := utils.NewSyncMap(), utils.NewSyncMap()
+			errs := make(chan error, 1)
+
+			passAt := -1
+			for i, status := range tc.statuses {
+				pod := &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod%d", i)},
+					Status:     v1.PodStatus{Phase: v1.PodPhase(status)},
+				}
+				task.verifyPod(context.Background(), pods, podMap, seen, pod, errs)
+				select {
+				case err := <-errs:
+					require.NoError(t, err)
+					if passAt < 0 {
+						passAt = i
+					}
+				default:
+				}
+			}
+			require.Equal(t, tc.passAt, passAt)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+	"github.com/NVIDIA/knavigator/pkg/utils"
+)
+
+// CheckResourceTask checks the state of any existing object selected by kind and name or selectors,
+// e.g. a ClusterQueue or a node not created by the workflow. It is a shorthand for CheckObjTask with a target.
+type CheckResourceTask struct {
+	CheckObjTask
+}
+
+type checkResourceTaskParams struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	// Namespace: the namespace of the namespaced objects
+	Namespace string `yaml:"namespace,omitempty"`
+	// Name: the name of the object; mutually exclusive with the selectors
+	Name          string `yaml:"name,omitempty"`
+	LabelSelector string `yaml:"labelSelector,omitempty"`
+	FieldSelector string `yaml:"fieldSelector,omitempty"`
+
+	State      map[string]interface{} `yaml:"state"`
+	Assert     []*utils.Assertion     `yaml:"assert,omitempty"`
+	Quantifier *Quantifier            `yaml:"quantifier,omitempty"`
+	Timeout    time.Duration          `yaml:"timeout"`
+}
+
+// newCheckResourceTask initializes and returns CheckResourceTask
+func newCheckResourceTask(client *dynamic.DynamicClient, accessor ObjInfoAccessor, cfg *config.Task) (*CheckResourceTask, error) {
+	if client == nil {
+		return nil, fmt.Errorf("%s/%s: DynamicClient is not set", cfg.Type, cfg.ID)
+	}
+
+	task := &CheckResourceTask{
+		CheckObjTask: CheckObjTask{
+			ObjStateTask: ObjStateTask{
+				BaseTask: BaseTask{
+					taskType: cfg.Type,
+					taskID:   cfg.ID,
+				},
+				client:   client,
+				accessor: accessor,
+			},
+		},
+	}
+
+	if err := task.validate(cfg.Params); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// validate initializes and validates parameters for CheckResourceTask, and converts them into the object target
+func (task *CheckResourceTask) validate(params map[string]interface{}) error {
+	data, err := yaml.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
+	}
+	var p checkResourceTaskParams
+	if err = yaml.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
+	}
+
+	if len(p.APIVersion) == 0 || len(p.Kind) == 0 {
+		return fmt.Errorf("%s: must specify apiVersion and kind", task.ID())
+	}
+	hasSelectors := len(p.LabelSelector) != 0 || len(p.FieldSelector) != 0
+	if (len(p.Name) != 0) == hasSelectors {
+		return fmt.Errorf("%s: must specify either name or selectors", task.ID())
+	}
+
+	target := &ObjTarget{
+		APIVersion:    p.APIVersion,
+		Kind:          p.Kind,
+		Namespace:     p.Namespace,
+		LabelSelector: p.LabelSelector,
+		FieldSelector: p.FieldSelector,
+	}
+	if len(p.Name) != 0 {
+		target.Names = &utils.NameSelector{List: &utils.NameList{Patterns: []string{p.Name}}}
+	}
+
+	task.StateParams = StateParams{
+		Target:     target,
+		State:      p.State,
+		Assert:     p.Assert,
+		Quantifier: p.Quantifier,
+		Timeout:    p.Timeout,
+	}
+
+	return task.validateState()
+}
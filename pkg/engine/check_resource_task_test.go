@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+	"github.com/NVIDIA/knavigator/pkg/utils"
+)
+
+func TestNewCheckResourceTask(t *testing.T) {
+	taskID := "check"
+	names := &utils.NameSelector{List: &utils.NameList{Patterns: []string{"cluster-queue"}}}
+	names.Init()
+	require.NoError(t, names.Finalize())
+
+	testCases := []struct {
+		name       string
+		simClients bool
+		params     map[string]interface{}
+		err        string
+		task       *CheckResourceTask
+	}{
+		{
+			name:   "Case 1: no client",
+			params: nil,
+			err:    "CheckResource/check: DynamicClient is not set",
+		},
+		{
+			name:       "Case 2: missing kind",
+			simClients: true,
+			params: map[string]interface{}{
+				"apiVersion": "kueue.x-k8s.io/v1beta1",
+				"name":       "cluster-queue",
+			},
+			err: "CheckResource/check: must specify apiVersion and kind",
+		},
+		{
+			name:       "Case 3: both name and selector",
+			simClients: true,
+			params: map[string]interface{}{
+				"apiVersion":    "kueue.x-k8s.io/v1beta1",
+				"kind":          "ClusterQueue",
+				"name":          "cluster-queue",
+				"labelSelector": "app=test",
+			},
+			err: "CheckResource/check: must specify either name or selectors",
+		},
+		{
+			name:       "Case 4: missing state",
+			simClients: true,
+			params: map[string]interface{}{
+				"apiVersion": "kueue.x-k8s.io/v1beta1",
+				"kind":       "ClusterQueue",
+				"name":       "cluster-queue",
+			},
+			err: "CheckResource/check: missing parameter 'state'",
+		},
+		{
+			name:       "Case 5: invalid selector",
+			simClients: true,
+			params: map[string]interface{}{
+				"apiVersion":    "v1",
+				"kind":          "Node",
+				"labelSelector": "a=b=c",
+				"state":         map[string]interface{}{"a": "b"},
+			},
+			err: `CheckResource/check: invalid target labelSelector "a=b=c": found '=', expected: ',' or 'end of string'`,
+		},
+		{
+			name:       "Case 6: valid name",
+			simClients: true,
+			params: map[string]interface{}{
+				"apiVersion": "kueue.x-k8s.io/v1beta1",
+				"kind":       "ClusterQueue",
+				"name":       "cluster-queue",
+				"state":      map[string]interface{}{"status": map[string]interface{}{"pendingWorkloads": 0}},
+				"timeout":    "1m",
+			},
+			task: &CheckResourceTask{
+				CheckObjTask: CheckObjTask{
+					ObjStateTask: ObjStateTask{
+						BaseTask: BaseTask{taskType: TaskCheckResource, taskID: taskID},
+						StateParams: StateParams{
+							Target: &ObjTarget{
+								APIVersion: "kueue.x-k8s.io/v1beta1",
+								Kind:       "ClusterQueue",
+								Names:      names,
+							},
+							State:   map[string]interface{}{"status": map[string]interface{}{"pendingWorkloads": 0}},
+							Timeout: time.Minute,
+						},
+						client: testDynamicClient,
+					},
+				},
+			},
+		},
+		{
+			name:       "Case 7: valid selector",
+			simClients: true,
+			params: map[string]interface{}{
+				"apiVersion":    "v1",
+				"kind":          "Node",
+				"labelSelector": "type=kwok",
+				"state":         map[string]interface{}{"status": map[string]interface{}{"allocatable": map[string]interface{}{"nvidia.com/gpu": "8"}}},
+				"quantifier":    map[string]interface{}{"type": "any"},
+			},
+			task: &CheckResourceTask{
+				CheckObjTask: CheckObjTask{
+					ObjStateTask: ObjStateTask{
+						BaseTask: BaseTask{taskType: TaskCheckResource, taskID: taskID},
+						StateParams: StateParams{
+							Target: &ObjTarget{
+								APIVersion:    "v1",
+								Kind:          "Node",
+								LabelSelector: "type=kwok",
+							},
+							State:      map[string]interface{}{"status": map[string]interface{}{"allocatable": map[string]interface{}{"nvidia.com/gpu": "8"}}},
+							Quantifier: &Quantifier{Type: QuantifierAny},
+						},
+						client: testDynamicClient,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			eng, err := New(nil, nil, tc.simClients)
+			require.NoError(t, err)
+
+			task, err := eng.GetTask(&config.Task{
+				ID:     taskID,
+				Type:   TaskCheckResource,
+				Params: tc.params,
+			})
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+				require.Nil(t, tc.task)
+			} else {
+				tc.task.accessor = eng
+				require.NoError(t, err)
+				require.NotNil(t, tc.task)
+				require.Equal(t, tc.task, task)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	log "k8s.io/klog/v2"
+)
+
+// GCOptions selects the objects deleted by the garbage collection
+type GCOptions struct {
+	// RunID: delete the objects of the workflow run; mutually exclusive with All
+	RunID string
+	// All: delete the objects of all workflow runs
+	All bool
+	// Configured: also delete the objects created by Configure tasks, such as virtual nodes, namespaces and priority classes
+	Configured bool
+}
+
+// configured resources are created by Configure tasks; they are deleted after the other objects,
+// since the namespaces contain them, and the virtual nodes run their pods
+var configuredResources = []schema.GroupVersionResource{
+	{Group: "", Version: "v1", Resource: "nodes"},
+	{Group: "scheduling.k8s.io", Version: "v1", Resource: "priorityclasses"},
+	{Group: "", Version: "v1", Resource: "namespaces"},
+}
+
+// Validate validates the garbage collection options
+func (opts *GCOptions) Validate() error {
+	if opts.All == (len(opts.RunID) != 0) {
+		return fmt.Errorf("must specify either run ID or all runs")
+	}
+	if len(opts.RunID) != 0 {
+		if errs := validation.IsValidLabelValue(opts.RunID); len(errs) != 0 {
+			return fmt.Errorf("invalid run ID %q: %s", opts.RunID, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// selector returns the label selector of the objects to delete
+func (opts *GCOptions) selector() (string, error) {
+	op, values := selection.Exists, []string{}
+	if !opts.All {
+		op, values = selection.Equals, []string{opts.RunID}
+	}
+	req, err := labels.NewRequirement(LabelRunID, op, values)
+	if err != nil {
+		return "", err
+	}
+	selector := labels.NewSelector().Add(*req)
+
+	// the objects created by Configure tasks are deleted on request only
+	if !opts.Configured {
+		if req, err = labels.NewRequirement(LabelConfigured, selection.DoesNotExist, nil); err != nil {
+			return "", err
+		}
+		selector = selector.Add(*req)
+	}
+
+	return selector.String(), nil
+}
+
+// CollectGarbage finds the objects created by knavigator by their tracking labels, and deletes them.
+// Unlike Eng.DeleteAllObjects, it does not rely on the state of the engine, and can clean up after a crashed run.
+func CollectGarbage(ctx context.Context, config *rest.Config, opts *GCOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	selector, err := opts.selector()
+	if err != nil {
+		return err
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	lists, err := discoveryClient.ServerPreferredResources()
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return fmt.Errorf("failed to discover API resources: %v", err)
+		}
+		log.Warningf("Partial API discovery: %v", err)
+	}
+
+	gc := &garbageCollector{client: dynamicClient, selector: selector}
+
+	for _, gvr := range gcResources(lists) {
+		gc.collect(ctx, gvr, false)
+	}
+	if opts.Configured {
+		for _, gvr := range configuredResources {
+			gc.collect(ctx, gvr, true)
+		}
+	}
+
+	log.Infof("Garbage collection with selector %q: deleted %d objects, failed to delete %d objects", selector, gc.deleted, gc.failed)
+
+	if gc.failed != 0 {
+		return fmt.Errorf("failed to delete %d objects", gc.failed)
+	}
+	return nil
+}
+
+// garbageCollector deletes the objects matching the label selector
+type garbageCollector struct {
+	client   *dynamic.DynamicClient
+	selector string
+	deleted  int
+	failed   int
+}
+
+// collect deletes the objects of the given resource
+func (gc *garbageCollector) collect(ctx context.Context, gvr schema.GroupVersionResource, configured bool) {
+	list, err := gc.client.Resource(gvr).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: gc.selector})
+	if err != nil {
+		log.Warningf("Failed to list %s: %v", gvr.String(), err)
+		gc.failed++
+		return
+	}
+
+	deletePolicy := metav1.DeletePropagationBackground
+	deletions := metav1.DeleteOptions{PropagationPolicy: &deletePolicy}
+
+	for _, obj := range list.Items {
+		// the objects spawned by the submitted objects, e.g. pods, are deleted by the Kubernetes garbage collector
+		if !configured && len(obj.GetOwnerReferences()) != 0 {
+			continue
+		}
+		err = gc.client.Resource(gvr).Namespace(obj.GetNamespace()).Delete(ctx, obj.GetName(), deletions)
+		switch {
+		case err == nil:
+			log.Infof("Deleted %s %s", gvr.Resource, objectKey(obj.GetNamespace(), obj.GetName()))
+			gc.deleted++
+		case errors.IsNotFound(err):
+		default:
+			log.Warningf("Failed to delete %s %s: %v", gvr.Resource, objectKey(obj.GetNamespace(), obj.GetName()), err)
+			gc.failed++
+		}
+	}
+}
+
+// gcResources returns the resources that can be listed and deleted, except for the configured resources
+func gcResources(lists []*metav1.APIResourceList) []schema.GroupVersionResource {
+	skip := make(map[schema.GroupResource]bool)
+	for _, gvr := range configuredResources {
+		skip[gvr.GroupResource()] = true
+	}
+
+	lists = discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list", "delete"}}, lists)
+
+	gvrs := []schema.GroupVersionResource{}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, res := range list.APIResources {
+			gvr := gv.WithResource(res.Name)
+			// skip subresources and events, which are not created by the workflows
+			if strings.Contains(res.Name, "/") || res.Name == "events" || skip[gvr.GroupResource()] {
+				continue
+			}
+			gvrs = append(gvrs, gvr)
+		}
+	}
+
+	return gvrs
+}
+
+// objectKey returns "namespace/name" of the namespaced objects, and "name" of the cluster-scoped objects
+func objectKey(namespace, name string) string {
+	if len(namespace) == 0 {
+		return name
+	}
+	return namespace + "/" + name
+}
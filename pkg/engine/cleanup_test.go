@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestGCOptions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     *GCOptions
+		selector string
+		err      string
+	}{
+		{
+			name: "Case 1: no runs",
+			opts: &GCOptions{},
+			err:  "must specify either run ID or all runs",
+		},
+		{
+			name: "Case 2: run ID and all runs",
+			opts: &GCOptions{RunID: "run1", All: true},
+			err:  "must specify either run ID or all runs",
+		},
+		{
+			name: "Case 3: invalid run ID",
+			opts: &GCOptions{RunID: "run/1"},
+			err:  `invalid run ID "run/1"`,
+		},
+		{
+			name:     "Case 4: run ID",
+			opts:     &GCOptions{RunID: "run1"},
+			selector: "!knavigator.io/configured,knavigator.io/run-id=run1",
+		},
+		{
+			name:     "Case 5: run ID with configured objects",
+			opts:     &GCOptions{RunID: "run1", Configured: true},
+			selector: "knavigator.io/run-id=run1",
+		},
+		{
+			name:     "Case 6: all runs",
+			opts:     &GCOptions{All: true, Configured: true},
+			selector: "knavigator.io/run-id",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.Validate()
+			if len(tc.err) != 0 {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			selector, err := tc.opts.selector()
+			require.NoError(t, err)
+			require.Equal(t, tc.selector, selector)
+		})
+	}
+}
+
+func TestGCResources(t *testing.T) {
+	verbs := metav1.Verbs{"create", "delete", "get", "list"}
+	lists := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "pods", Namespaced: true, Verbs: verbs},
+				{Name: "pods/status", Namespaced: true, Verbs: metav1.Verbs{"get", "patch"}},
+				{Name: "events", Namespaced: true, Verbs: verbs},
+				{Name: "nodes", Verbs: verbs},
+				{Name: "namespaces", Verbs: verbs},
+				{Name: "bindings", Namespaced: true, Verbs: metav1.Verbs{"create"}},
+			},
+		},
+		{
+			GroupVersion: "batch/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "jobs", Namespaced: true, Verbs: verbs},
+			},
+		},
+		{
+			GroupVersion: "scheduling.k8s.io/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "priorityclasses", Verbs: verbs},
+			},
+		},
+	}
+
+	require.Equal(t, []schema.GroupVersionResource{
+		{Group: "", Version: "v1", Resource: "pods"},
+		{Group: "batch", Version: "v1", Resource: "jobs"},
+	}, gcResources(lists))
+}
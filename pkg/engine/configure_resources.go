@@ -0,0 +1,593 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+	log "k8s.io/klog/v2"
+	sigyaml "sigs.k8s.io/yaml"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+)
+
+// resourceQuota describes a ResourceQuota; Spec follows the ResourceQuota spec, e.g. {"hard": {"cpu": "10"}}
+type resourceQuota struct {
+	Name      string                 `yaml:"name"`
+	Namespace string                 `yaml:"namespace"`
+	Spec      map[string]interface{} `yaml:"spec,omitempty"`
+	Op        string                 `yaml:"op"`
+}
+
+// limitRange describes a LimitRange; Spec follows the LimitRange spec, e.g. {"limits": [{"type": "Container", ...}]}
+type limitRange struct {
+	Name      string                 `yaml:"name"`
+	Namespace string                 `yaml:"namespace"`
+	Spec      map[string]interface{} `yaml:"spec,omitempty"`
+	Op        string                 `yaml:"op"`
+}
+
+type serviceAccount struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+	Op        string `yaml:"op"`
+}
+
+// roleBinding describes a RoleBinding, or a ClusterRoleBinding if the namespace is not set
+type roleBinding struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	RoleRef   roleRef           `yaml:"roleRef"`
+	Subjects  []rbacSubject     `yaml:"subjects"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+	Op        string            `yaml:"op"`
+}
+
+type roleRef struct {
+	// Kind: "Role" or "ClusterRole"
+	Kind string `yaml:"kind" json:"kind"`
+	Name string `yaml:"name" json:"name"`
+}
+
+type rbacSubject struct {
+	// Kind: "ServiceAccount", "User" or "Group"
+	Kind      string `yaml:"kind" json:"kind"`
+	Name      string `yaml:"name" json:"name"`
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+}
+
+// nodeTaint adds ("create") or removes ("delete") taints of the nodes selected by names and/or labels
+type nodeTaint struct {
+	Names  []string          `yaml:"names,omitempty"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+	Taints []taint           `yaml:"taints"`
+	Op     string            `yaml:"op"`
+}
+
+type taint struct {
+	Key   string `yaml:"key"`
+	Value string `yaml:"value,omitempty"`
+	// Effect: "NoSchedule", "PreferNoSchedule" or "NoExecute"; optional when removing taints
+	Effect string `yaml:"effect,omitempty"`
+}
+
+// manifest applies ("create") or deletes ("delete") the objects from the manifest;
+// Template is a path to a manifest file, a directory, a glob pattern, or an inline manifest
+type manifest struct {
+	Template string `yaml:"template"`
+	Op       string `yaml:"op"`
+
+	// derived
+	objs []*unstructured.Unstructured
+}
+
+// configObject is an object created or deleted by Configure task
+type configObject struct {
+	obj *unstructured.Unstructured
+	op  string
+}
+
+// validateOp validates the create/delete operation of the Configure task section
+func (task *ConfigureTask) validateOp(kind, op string) error {
+	switch op {
+	case OpCreate, OpDelete:
+		return nil
+	default:
+		return fmt.Errorf("%s: invalid %s operation %s; supported: %s, %s", task.ID(), kind, op, OpCreate, OpDelete)
+	}
+}
+
+// validateResources validates the sections of the resources configured with the dynamic client,
+// and converts them into the objects to create or delete
+func (task *ConfigureTask) validateResources(templates map[string]string) error {
+	task.objects = []*configObject{}
+
+	for _, sa := range task.ServiceAccounts {
+		if err := task.validateOp("ServiceAccount", sa.Op); err != nil {
+			return err
+		}
+		if len(sa.Name) == 0 || len(sa.Namespace) == 0 {
+			return fmt.Errorf("%s: must provide name and namespace of ServiceAccount", task.ID())
+		}
+		task.addObject(newConfigObject("v1", "ServiceAccount", sa.Name, sa.Namespace, nil), sa.Op)
+	}
+
+	for _, rb := range task.RoleBindings {
+		if err := task.validateOp("RoleBinding", rb.Op); err != nil {
+			return err
+		}
+		if len(rb.Name) == 0 {
+			return fmt.Errorf("%s: must provide name of RoleBinding", task.ID())
+		}
+		kind := "RoleBinding"
+		if len(rb.Namespace) == 0 {
+			kind = "ClusterRoleBinding"
+		}
+		fields := map[string]interface{}{}
+		if rb.Op == OpCreate {
+			if (rb.RoleRef.Kind != "Role" && rb.RoleRef.Kind != "ClusterRole") || len(rb.RoleRef.Name) == 0 {
+				return fmt.Errorf("%s: %s %s must refer to Role or ClusterRole by name", task.ID(), kind, rb.Name)
+			}
+			if len(rb.Subjects) == 0 {
+				return fmt.Errorf("%s: %s %s must have subjects", task.ID(), kind, rb.Name)
+			}
+			subjects := make([]interface{}, 0, len(rb.Subjects))
+			for _, s := range rb.Subjects {
+				subject := map[string]interface{}{"kind": s.Kind, "name": s.Name}
+				switch s.Kind {
+				case "ServiceAccount":
+					if len(s.Namespace) == 0 {
+						return fmt.Errorf("%s: %s %s: must provide namespace of ServiceAccount %s", task.ID(), kind, rb.Name, s.Name)
+					}
+					subject["namespace"] = s.Namespace
+				case "User", "Group":
+					subject["apiGroup"] = "rbac.authorization.k8s.io"
+				default:
+					return fmt.Errorf("%s: %s %s: invalid subject kind %q", task.ID(), kind, rb.Name, s.Kind)
+				}
+				subjects = append(subjects, subject)
+			}
+			fields["roleRef"] = map[string]interface{}{
+				"apiGroup": "rbac.authorization.k8s.io",
+				"kind":     rb.RoleRef.Kind,
+				"name":     rb.RoleRef.Name,
+			}
+			fields["subjects"] = subjects
+		}
+		obj := newConfigObject("rbac.authorization.k8s.io/v1", kind, rb.Name, rb.Namespace, fields)
+		if len(rb.Labels) != 0 {
+			obj.SetLabels(rb.Labels)
+		}
+		task.addObject(obj, rb.Op)
+	}
+
+	for _, rq := range task.ResourceQuotas {
+		if err := task.validateOp("ResourceQuota", rq.Op); err != nil {
+			return err
+		}
+		if len(rq.Name) == 0 || len(rq.Namespace) == 0 {
+			return fmt.Errorf("%s: must provide name and namespace of ResourceQuota", task.ID())
+		}
+		if rq.Op == OpCreate && len(rq.Spec) == 0 {
+			return fmt.Errorf("%s: must provide spec when creating ResourceQuota %s", task.ID(), rq.Name)
+		}
+		fields, err := specFields(rq.Spec)
+		if err != nil {
+			return fmt.Errorf("%s: invalid spec of ResourceQuota %s: %v", task.ID(), rq.Name, err)
+		}
+		task.addObject(newConfigObject("v1", "ResourceQuota", rq.Name, rq.Namespace, fields), rq.Op)
+	}
+
+	for _, lr := range task.LimitRanges {
+		if err := task.validateOp("LimitRange", lr.Op); err != nil {
+			return err
+		}
+		if len(lr.Name) == 0 || len(lr.Namespace) == 0 {
+			return fmt.Errorf("%s: must provide name and namespace of LimitRange", task.ID())
+		}
+		if lr.Op == OpCreate && len(lr.Spec) == 0 {
+			return fmt.Errorf("%s: must provide spec when creating LimitRange %s", task.ID(), lr.Name)
+		}
+		fields, err := specFields(lr.Spec)
+		if err != nil {
+			return fmt.Errorf("%s: invalid spec of LimitRange %s: %v", task.ID(), lr.Name, err)
+		}
+		task.addObject(newConfigObject("v1", "LimitRange", lr.Name, lr.Namespace, fields), lr.Op)
+	}
+
+	for _, m := range task.Manifests {
+		if err := task.validateOp("manifest", m.Op); err != nil {
+			return err
+		}
+		if len(m.Template) == 0 {
+			return fmt.Errorf("%s: must provide manifest template", task.ID())
+		}
+		objs, err := loadManifest(m.Template, templates)
+		if err != nil {
+			return fmt.Errorf("%s: %v", task.ID(), err)
+		}
+		for _, obj := range objs {
+			task.addObject(obj, m.Op)
+		}
+	}
+
+	for _, nt := range task.NodeTaints {
+		if err := task.validateOp("node taint", nt.Op); err != nil {
+			return err
+		}
+		if len(nt.Names) == 0 && len(nt.Labels) == 0 {
+			return fmt.Errorf("%s: must provide node names and/or labels for node taints", task.ID())
+		}
+		if len(nt.Taints) == 0 {
+			return fmt.Errorf("%s: missing node taints", task.ID())
+		}
+		for _, t := range nt.Taints {
+			if len(t.Key) == 0 {
+				return fmt.Errorf("%s: must provide taint key", task.ID())
+			}
+			switch corev1.TaintEffect(t.Effect) {
+			case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+			case "":
+				if nt.Op == OpCreate {
+					return fmt.Errorf("%s: must provide effect of taint %s", task.ID(), t.Key)
+				}
+			default:
+				return fmt.Errorf("%s: invalid effect of taint %s: %s", task.ID(), t.Key, t.Effect)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (task *ConfigureTask) addObject(obj *unstructured.Unstructured, op string) {
+	task.objects = append(task.objects, &configObject{obj: obj, op: op})
+}
+
+// newConfigObject returns the object with the given top-level fields
+func newConfigObject(apiVersion, kind, name, namespace string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	for key, val := range fields {
+		obj.Object[key] = val
+	}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	return obj
+}
+
+// specFields returns the spec as the top-level fields of the object.
+// The spec is converted to JSON types, as required by the unstructured objects.
+func specFields(spec map[string]interface{}) (map[string]interface{}, error) {
+	if len(spec) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(convertMap(spec))
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]interface{}{}
+	if err = utiljson.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"spec": out}, nil
+}
+
+// loadManifest returns the objects from the manifest bundled with the workflow, or from the local files
+func loadManifest(ref string, templates map[string]string) ([]*unstructured.Unstructured, error) {
+	data, ok := templates[ref]
+	if !ok {
+		var err error
+		if data, err = config.LoadTemplate(ref); err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %v", err)
+		}
+	}
+
+	objs := []*unstructured.Unstructured{}
+	for _, block := range reDelim.Split(data, -1) {
+		if len(strings.TrimSpace(block)) == 0 {
+			continue
+		}
+		jsonData, err := sigyaml.YAMLToJSON([]byte(block))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse manifest: %v", err)
+		}
+		obj := &unstructured.Unstructured{}
+		if err = utiljson.Unmarshal(jsonData, &obj.Object); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest: %v", err)
+		}
+		if len(obj.GetAPIVersion()) == 0 || len(obj.GetKind()) == 0 || len(obj.GetName()) == 0 {
+			return nil, fmt.Errorf("manifest objects must have apiVersion, kind and metadata.name")
+		}
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+// updateObjects creates, updates or deletes the objects in order, using the dynamic client
+func (task *ConfigureTask) updateObjects(ctx context.Context, trackingLabels map[string]string) error {
+	for _, o := range task.objects {
+		gvr, namespaced, err := task.accessor.GetGVR(o.obj.GroupVersionKind())
+		if err != nil {
+			return fmt.Errorf("%s: %v", task.ID(), err)
+		}
+		obj := o.obj.DeepCopy()
+		if !namespaced {
+			obj.SetNamespace("")
+		}
+		client := task.dynClient.Resource(gvr).Namespace(obj.GetNamespace())
+		desc := fmt.Sprintf("%s %s", obj.GetKind(), objectKey(obj.GetNamespace(), obj.GetName()))
+		log.Infof("%s %s", o.op, desc)
+
+		curObj, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("%s: failed to get %s: %v", task.ID(), desc, err)
+		}
+		exists := err == nil
+
+		switch o.op {
+		case OpCreate:
+			if exists {
+				if err = mergeObject(ctx, client, obj); err != nil {
+					return fmt.Errorf("%s: failed to update %s: %v", task.ID(), desc, err)
+				}
+				log.Infof("Updated %s", desc)
+				task.record(stageObjects, "restore "+desc, func(ctx context.Context) error {
+					return restoreObject(ctx, client, curObj)
+				})
+			} else {
+				objLabels := obj.GetLabels()
+				if objLabels == nil {
+					objLabels = make(map[string]string)
+				}
+				for key, val := range trackingLabels {
+					objLabels[key] = val
+				}
+				obj.SetLabels(objLabels)
+				if _, err = client.Create(ctx, obj, metav1.CreateOptions{}); err != nil {
+					return fmt.Errorf("%s: failed to create %s: %v", task.ID(), desc, err)
+				}
+				log.Infof("Created %s", desc)
+				task.record(stageObjects, "delete "+desc, func(ctx context.Context) error {
+					return ignoreNotFound(client.Delete(ctx, obj.GetName(), metav1.DeleteOptions{}))
+				})
+			}
+
+		case OpDelete:
+			if !exists {
+				log.V(4).Infof("%s does not exist; nothing to delete", desc)
+				continue
+			}
+			if err = client.Delete(ctx, obj.GetName(), metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("%s: failed to delete %s: %v", task.ID(), desc, err)
+			}
+			log.Infof("Deleted %s", desc)
+			task.record(stageObjects, "re-create "+desc, func(ctx context.Context) error {
+				return restoreObject(ctx, client, curObj)
+			})
+		}
+	}
+
+	return nil
+}
+
+// mergeObject merges the manifest into the existing object, preserving the fields set by others.
+// If the manifest changes immutable fields, e.g. roleRef of a RoleBinding, the object is re-created.
+func mergeObject(ctx context.Context, client dynamic.ResourceInterface, obj *unstructured.Unstructured) error {
+	patch, err := json.Marshal(obj.Object)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Patch(ctx, obj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+	if !errors.IsInvalid(err) {
+		return err
+	}
+
+	log.Infof("Re-creating %s %s: %v", obj.GetKind(), objectKey(obj.GetNamespace(), obj.GetName()), err)
+	if err = client.Delete(ctx, obj.GetName(), metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+	return err
+}
+
+// restoreObject reinstates the original object, creating it if it does not exist
+func restoreObject(ctx context.Context, client dynamic.ResourceInterface, orig *unstructured.Unstructured) error {
+	obj := orig.DeepCopy()
+	unstructured.RemoveNestedField(obj.Object, "metadata", "uid")
+	unstructured.RemoveNestedField(obj.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
+	unstructured.RemoveNestedField(obj.Object, "metadata", "generation")
+	unstructured.RemoveNestedField(obj.Object, "status")
+
+	cur, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		obj.SetResourceVersion("")
+		_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	obj.SetResourceVersion(cur.GetResourceVersion())
+	_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+	return err
+}
+
+// updateNamespaceLabels merges the labels into the existing namespaces
+func (task *ConfigureTask) updateNamespaceLabels(ctx context.Context) error {
+	for _, ns := range task.Namespaces {
+		if ns.Op != OpCreate || len(ns.Labels) == 0 {
+			continue
+		}
+		log.Infof("Labeling namespace %s with %v", ns.Name, ns.Labels)
+
+		var orig map[string]string
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			obj, err := task.client.CoreV1().Namespaces().Get(ctx, ns.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			orig = obj.Labels
+			obj.Labels = make(map[string]string, len(orig)+len(ns.Labels))
+			for key, val := range orig {
+				obj.Labels[key] = val
+			}
+			for key, val := range ns.Labels {
+				obj.Labels[key] = val
+			}
+			_, err = task.client.CoreV1().Namespaces().Update(ctx, obj, metav1.UpdateOptions{})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%s: failed to label namespace %s: %v", task.ID(), ns.Name, err)
+		}
+
+		name := ns.Name
+		task.record(stageObjects, "restore labels of namespace "+name, func(ctx context.Context) error {
+			return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				obj, err := task.client.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return ignoreNotFound(err)
+				}
+				obj.Labels = orig
+				_, err = task.client.CoreV1().Namespaces().Update(ctx, obj, metav1.UpdateOptions{})
+				return err
+			})
+		})
+	}
+
+	return nil
+}
+
+// updateNodeTaints adds or removes the taints of the selected nodes
+func (task *ConfigureTask) updateNodeTaints(ctx context.Context) error {
+	for _, nt := range task.NodeTaints {
+		names, err := task.taintedNodes(ctx, &nt)
+		if err != nil {
+			return err
+		}
+		log.Infof("%s taints %v of %d nodes", nt.Op, nt.Taints, len(names))
+
+		for _, name := range names {
+			var orig []corev1.Taint
+			err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				node, err := task.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				orig = node.Spec.Taints
+				node.Spec.Taints = applyTaints(node.Spec.Taints, nt.Taints, nt.Op)
+				_, err = task.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("%s: failed to update taints of node %s: %v", task.ID(), name, err)
+			}
+
+			task.record(stageObjects, "restore taints of node "+name, func(ctx context.Context) error {
+				return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+					node, err := task.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+					if err != nil {
+						return ignoreNotFound(err)
+					}
+					node.Spec.Taints = orig
+					_, err = task.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+					return err
+				})
+			})
+		}
+	}
+
+	return nil
+}
+
+// taintedNodes returns the names of the nodes selected by names and labels
+func (task *ConfigureTask) taintedNodes(ctx context.Context, nt *nodeTaint) ([]string, error) {
+	if len(nt.Labels) == 0 {
+		return nt.Names, nil
+	}
+
+	list, err := task.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(nt.Labels).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to list nodes: %v", task.ID(), err)
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range nt.Names {
+		selected[name] = true
+	}
+	names := []string{}
+	for _, node := range list.Items {
+		if len(nt.Names) == 0 || selected[node.Name] {
+			names = append(names, node.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s: no nodes matched the node taint selectors", task.ID())
+	}
+
+	return names, nil
+}
+
+// applyTaints returns the node taints with the taints added or removed.
+// The added taints replace the taints with the same key and effect;
+// the removed taints match by key, and by effect if specified.
+func applyTaints(nodeTaints []corev1.Taint, taints []taint, op string) []corev1.Taint {
+	matches := func(nodeTaint corev1.Taint, t taint) bool {
+		return nodeTaint.Key == t.Key && (len(t.Effect) == 0 || nodeTaint.Effect == corev1.TaintEffect(t.Effect))
+	}
+
+	out := []corev1.Taint{}
+	for _, nodeTaint := range nodeTaints {
+		keep := true
+		for _, t := range taints {
+			if matches(nodeTaint, t) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out = append(out, nodeTaint)
+		}
+	}
+
+	if op == OpCreate {
+		for _, t := range taints {
+			out = append(out, corev1.Taint{Key: t.Key, Value: t.Value, Effect: corev1.TaintEffect(t.Effect)})
+		}
+	}
+
+	return out
+}
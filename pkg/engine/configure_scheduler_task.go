@@ -0,0 +1,241 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	log "k8s.io/klog/v2"
+	sigyaml "sigs.k8s.io/yaml"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+)
+
+// ConfigureSchedulerTask patches the scheduler configuration stored in a configmap,
+// and restarts the scheduler controller to apply it
+type ConfigureSchedulerTask struct {
+	BaseTask
+	configureSchedulerTaskParams
+
+	client *kubernetes.Clientset
+}
+
+type configureSchedulerTaskParams struct {
+	// Configmap: the configmap with the scheduler configuration
+	Configmap configmapRef `yaml:"configmap"`
+	// Key: the configmap key with the YAML or JSON configuration document
+	Key string `yaml:"key"`
+	// Patch: the patch merged into the configuration document. Maps are merged recursively,
+	// other values, including lists, replace the original ones, and null values remove them.
+	Patch map[string]interface{} `yaml:"patch"`
+	// Restart: the scheduler controller to restart after the update; the namespace defaults to the configmap namespace.
+	// Omit it for the schedulers that reload the configuration on their own.
+	Restart *deploymentRestart `yaml:"restart,omitempty"`
+
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type configmapRef struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+// newConfigureSchedulerTask initializes and returns ConfigureSchedulerTask
+func newConfigureSchedulerTask(client *kubernetes.Clientset, cfg *config.Task) (*ConfigureSchedulerTask, error) {
+	if client == nil {
+		return nil, fmt.Errorf("%s/%s: Kubernetes client is not set", cfg.Type, cfg.ID)
+	}
+
+	task := &ConfigureSchedulerTask{
+		BaseTask: BaseTask{
+			taskType: TaskConfigureScheduler,
+			taskID:   cfg.ID,
+		},
+		client: client,
+	}
+
+	if err := task.validate(cfg.Params); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// validate initializes and validates parameters for ConfigureSchedulerTask
+func (task *ConfigureSchedulerTask) validate(params map[string]interface{}) error {
+	data, err := yaml.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
+	}
+	if err = yaml.Unmarshal(data, &task.configureSchedulerTaskParams); err != nil {
+		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
+	}
+
+	if len(task.Configmap.Name) == 0 || len(task.Configmap.Namespace) == 0 {
+		return fmt.Errorf("%s: must specify configmap name and namespace", task.ID())
+	}
+	if len(task.Key) == 0 {
+		return fmt.Errorf("%s: missing parameter 'key'", task.ID())
+	}
+	if len(task.Patch) == 0 {
+		return fmt.Errorf("%s: missing parameter 'patch'", task.ID())
+	}
+
+	if task.Restart != nil {
+		if len(task.Restart.Namespace) == 0 {
+			task.Restart.Namespace = task.Configmap.Namespace
+		}
+		if err = task.Restart.validate(); err != nil {
+			return fmt.Errorf("%s: %v", task.ID(), err)
+		}
+	}
+
+	if task.Timeout == 0 {
+		return fmt.Errorf("%s: missing parameter 'timeout'", task.ID())
+	}
+
+	return nil
+}
+
+// Exec implements Runnable interface
+func (task *ConfigureSchedulerTask) Exec(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+	defer cancel()
+
+	cmKey := objectKey(task.Configmap.Namespace, task.Configmap.Name)
+	cmClient := task.client.CoreV1().ConfigMaps(task.Configmap.Namespace)
+
+	updated := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := cmClient.Get(ctx, task.Configmap.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		doc, ok := cm.Data[task.Key]
+		if !ok {
+			return fmt.Errorf("key %q not found", task.Key)
+		}
+
+		newDoc, err := patchDocument(doc, task.Patch)
+		if err != nil {
+			return fmt.Errorf("failed to patch key %q: %v", task.Key, err)
+		}
+		if newDoc == doc {
+			return nil
+		}
+
+		cm.Data[task.Key] = newDoc
+		if _, err = cmClient.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		updated = true
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: failed to update configmap %s: %v", task.ID(), cmKey, err)
+	}
+
+	if !updated {
+		log.Infof("Configmap %s is up to date", cmKey)
+		return nil
+	}
+	log.Infof("Configmap %s updated", cmKey)
+
+	if task.Restart != nil {
+		if err = restartWorkloads(ctx, task.client, task.Restart); err != nil {
+			return fmt.Errorf("%s: %v", task.ID(), err)
+		}
+	}
+
+	return nil
+}
+
+// patchDocument merges the patch into the YAML or JSON document, and returns the document in the original format.
+// The YAML documents are re-serialized with sorted keys, and the comments are not preserved.
+func patchDocument(doc string, patch map[string]interface{}) (string, error) {
+	orig := map[string]interface{}{}
+	if err := sigyaml.Unmarshal([]byte(doc), &orig); err != nil {
+		return "", err
+	}
+
+	// the patch is converted to the same types as the document
+	data, err := json.Marshal(convertMap(patch))
+	if err != nil {
+		return "", err
+	}
+	jsonPatch := map[string]interface{}{}
+	if err = json.Unmarshal(data, &jsonPatch); err != nil {
+		return "", err
+	}
+
+	merged := mergeDocument(orig, jsonPatch)
+	if mapsEqual(orig, merged) {
+		return doc, nil
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(doc), "{") {
+		data, err = json.MarshalIndent(merged, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	}
+
+	if data, err = sigyaml.Marshal(merged); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// mergeDocument merges the patch into the document following JSON merge patch semantics (RFC 7386)
+func mergeDocument(doc, patch map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(doc))
+	for key, val := range doc {
+		out[key] = val
+	}
+
+	for key, val := range patch {
+		if val == nil {
+			delete(out, key)
+			continue
+		}
+		patchMap, ok := val.(map[string]interface{})
+		if !ok {
+			out[key] = val
+			continue
+		}
+		docMap, _ := out[key].(map[string]interface{})
+		out[key] = mergeDocument(docMap, patchMap)
+	}
+
+	return out
+}
+
+// mapsEqual returns true if the documents have the same JSON representation
+func mapsEqual(a, b map[string]interface{}) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(dataA) == string(dataB)
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+)
+
+func TestNewConfigureSchedulerTask(t *testing.T) {
+	taskID := "scheduler"
+	testCases := []struct {
+		name       string
+		simClients bool
+		params     map[string]interface{}
+		err        string
+		task       *ConfigureSchedulerTask
+	}{
+		{
+			name:   "Case 1: no k8s client",
+			params: nil,
+			err:    "ConfigureScheduler/scheduler: Kubernetes client is not set",
+		},
+		{
+			name:       "Case 2: no params",
+			simClients: true,
+			params:     nil,
+			err:        "ConfigureScheduler/scheduler: must specify configmap name and namespace",
+		},
+		{
+			name:       "Case 3: missing key",
+			simClients: true,
+			params: map[string]interface{}{
+				"configmap": map[string]interface{}{"name": "kueue-manager-config", "namespace": "kueue-system"},
+			},
+			err: "ConfigureScheduler/scheduler: missing parameter 'key'",
+		},
+		{
+			name:       "Case 4: missing patch",
+			simClients: true,
+			params: map[string]interface{}{
+				"configmap": map[string]interface{}{"name": "kueue-manager-config", "namespace": "kueue-system"},
+				"key":       "controller_manager_config.yaml",
+			},
+			err: "ConfigureScheduler/scheduler: missing parameter 'patch'",
+		},
+		{
+			name:       "Case 5: invalid restart",
+			simClients: true,
+			params: map[string]interface{}{
+				"configmap": map[string]interface{}{"name": "kueue-manager-config", "namespace": "kueue-system"},
+				"key":       "controller_manager_config.yaml",
+				"patch":     map[string]interface{}{"waitForPodsReady": map[string]interface{}{"enable": true}},
+				"restart":   map[string]interface{}{"kind": "Job", "name": "kueue-controller-manager"},
+			},
+			err: "ConfigureScheduler/scheduler: invalid restart kind Job; supported: Deployment, StatefulSet, DaemonSet",
+		},
+		{
+			name:       "Case 6: missing timeout",
+			simClients: true,
+			params: map[string]interface{}{
+				"configmap": map[string]interface{}{"name": "kueue-manager-config", "namespace": "kueue-system"},
+				"key":       "controller_manager_config.yaml",
+				"patch":     map[string]interface{}{"waitForPodsReady": map[string]interface{}{"enable": true}},
+			},
+			err: "ConfigureScheduler/scheduler: missing parameter 'timeout'",
+		},
+		{
+			name:       "Case 7: valid input",
+			simClients: true,
+			params: map[string]interface{}{
+				"configmap": map[string]interface{}{"name": "kueue-manager-config", "namespace": "kueue-system"},
+				"key":       "controller_manager_config.yaml",
+				"patch":     map[string]interface{}{"waitForPodsReady": map[string]interface{}{"enable": true}},
+				"restart":   map[string]interface{}{"name": "kueue-controller-manager"},
+				"timeout":   "5m",
+			},
+			task: &ConfigureSchedulerTask{
+				BaseTask: BaseTask{taskType: TaskConfigureScheduler, taskID: taskID},
+				configureSchedulerTaskParams: configureSchedulerTaskParams{
+					Configmap: configmapRef{Name: "kueue-manager-config", Namespace: "kueue-system"},
+					Key:       "controller_manager_config.yaml",
+					Patch:     map[string]interface{}{"waitForPodsReady": map[string]interface{}{"enable": true}},
+					Restart:   &deploymentRestart{Name: "kueue-controller-manager", Namespace: "kueue-system"},
+					Timeout:   5 * time.Minute,
+				},
+				client: testK8sClient,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			eng, err := New(nil, nil, tc.simClients)
+			require.NoError(t, err)
+			task, err := eng.GetTask(&config.Task{
+				ID:     taskID,
+				Type:   TaskConfigureScheduler,
+				Params: tc.params,
+			})
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+				require.Nil(t, tc.task)
+			} else {
+				require.NoError(t, err)
+				require.NotNil(t, tc.task)
+				require.Equal(t, tc.task, task)
+			}
+		})
+	}
+}
+
+func TestPatchDocument(t *testing.T) {
+	testCases := []struct {
+		name  string
+		doc   string
+		patch map[string]interface{}
+		exp   string
+		err   string
+	}{
+		{
+			name: "Case 1: merge into YAML document",
+			doc: `apiVersion: config.kueue.x-k8s.io/v1beta1
+kind: Configuration
+waitForPodsReady:
+  enable: false
+  timeout: 5m
+integrations:
+  frameworks:
+  - batch/job
+`,
+			patch: map[string]interface{}{
+				"waitForPodsReady": map[string]interface{}{"enable": true},
+				"integrations":     map[string]interface{}{"frameworks": []interface{}{"batch/job", "jobset.x-k8s.io/jobset"}},
+			},
+			exp: `apiVersion: config.kueue.x-k8s.io/v1beta1
+integrations:
+  frameworks:
+  - batch/job
+  - jobset.x-k8s.io/jobset
+kind: Configuration
+waitForPodsReady:
+  enable: true
+  timeout: 5m
+`,
+		},
+		{
+			name:  "Case 2: merge into JSON document and remove key",
+			doc:   `{"placementRules": [{"name": "tag"}], "partitions": {"default": {"preemption": true}}, "limits": 10}`,
+			patch: map[string]interface{}{"limits": nil, "partitions": map[string]interface{}{"default": map[string]interface{}{"preemption": false}}},
+			exp: `{
+  "partitions": {
+    "default": {
+      "preemption": false
+    }
+  },
+  "placementRules": [
+    {
+      "name": "tag"
+    }
+  ]
+}
+`,
+		},
+		{
+			name:  "Case 3: document is up to date",
+			doc:   "# volcano\nactions: \"enqueue, allocate\"\n",
+			patch: map[string]interface{}{"actions": "enqueue, allocate"},
+			exp:   "# volcano\nactions: \"enqueue, allocate\"\n",
+		},
+		{
+			name:  "Case 4: invalid document",
+			doc:   "- a\n- b\n",
+			patch: map[string]interface{}{"key": "val"},
+			err:   "cannot unmarshal array",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := patchDocument(tc.doc, tc.patch)
+			if len(tc.err) != 0 {
+				require.ErrorContains(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.exp, doc)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	log "k8s.io/klog/v2"
+)
+
+// restore stages: the objects are restored before the namespaces, which may contain them
+const (
+	stageObjects = iota
+	stageNamespaces
+	numStages
+)
+
+// configSnapshot records the steps restoring the cluster state changed by Configure task
+type configSnapshot struct {
+	mutex sync.Mutex
+	steps [numStages][]restoreStep
+}
+
+type restoreStep struct {
+	desc string
+	fn   func(context.Context) error
+}
+
+// add records the restore step
+func (s *configSnapshot) add(stage int, desc string, fn func(context.Context) error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.steps[stage] = append(s.steps[stage], restoreStep{desc: desc, fn: fn})
+}
+
+// restore runs the restore steps stage by stage, in the reverse order of the changes within each stage.
+// It runs all steps, and returns an error listing the failed ones.
+func (s *configSnapshot) restore(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	failed := []string{}
+	for stage := range s.steps {
+		steps := s.steps[stage]
+		for i := len(steps) - 1; i >= 0; i-- {
+			log.Infof("Restore: %s", steps[i].desc)
+			if err := steps[i].fn(ctx); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", steps[i].desc, err))
+			}
+		}
+	}
+
+	if len(failed) != 0 {
+		return fmt.Errorf("failed to restore %d objects: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// record records the restore step if the task restores the cluster state
+func (task *ConfigureTask) record(stage int, desc string, fn func(context.Context) error) {
+	if task.snapshot != nil {
+		task.snapshot.add(stage, desc, fn)
+	}
+}
+
+// recordPriorityClass records the step restoring the original PriorityClass.
+// Since the value of PriorityClass is immutable, the class is re-created.
+func (task *ConfigureTask) recordPriorityClass(curObj *schedulingv1.PriorityClass) {
+	oldObj := curObj.DeepCopy()
+	oldObj.ObjectMeta = restorableMeta(&curObj.ObjectMeta)
+
+	task.record(stageObjects, "restore PriorityClass "+oldObj.Name, func(ctx context.Context) error {
+		client := task.client.SchedulingV1().PriorityClasses()
+		obj, err := client.Get(ctx, oldObj.Name, metav1.GetOptions{})
+		if err == nil {
+			if obj.Value == oldObj.Value {
+				return nil
+			}
+			if err = client.Delete(ctx, oldObj.Name, metav1.DeleteOptions{}); err != nil {
+				return err
+			}
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+		_, err = client.Create(ctx, oldObj, metav1.CreateOptions{})
+		return err
+	})
+}
+
+// recordConfigmap records the step restoring the original ConfigMap
+func (task *ConfigureTask) recordConfigmap(curObj *corev1.ConfigMap) {
+	oldObj := curObj.DeepCopy()
+	oldObj.ObjectMeta = restorableMeta(&curObj.ObjectMeta)
+
+	task.record(stageObjects, "restore configmap "+objectKey(oldObj.Namespace, oldObj.Name), func(ctx context.Context) error {
+		client := task.client.CoreV1().ConfigMaps(oldObj.Namespace)
+		_, err := client.Update(ctx, oldObj, metav1.UpdateOptions{})
+		if errors.IsNotFound(err) {
+			_, err = client.Create(ctx, oldObj, metav1.CreateOptions{})
+		}
+		return err
+	})
+}
+
+// restorableMeta returns the object metadata without the fields set by the API server
+func restorableMeta(meta *metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        meta.Name,
+		Namespace:   meta.Namespace,
+		Labels:      meta.Labels,
+		Annotations: meta.Annotations,
+	}
+}
+
+// createNamespace creates the namespace, waiting for the termination of the deleted namespace with the same name
+func createNamespace(ctx context.Context, client *kubernetes.Clientset, ns *corev1.Namespace) error {
+	for {
+		_, err := client.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+		if !errors.IsAlreadyExists(err) {
+			return err
+		}
+
+		cur, err := client.CoreV1().Namespaces().Get(ctx, ns.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && cur.DeletionTimestamp == nil {
+			log.Infof("Namespace %s already exist", ns.Name)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(deletionPollInterval):
+		}
+	}
+}
+
+// ignoreNotFound returns nil on NotFound errors
+func ignoreNotFound(err error) error {
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// recordVirtualNodes records the step restoring the virtual nodes: the helm release is rolled back
+// to its current revision, or uninstalled if it does not exist yet
+func (task *ConfigureTask) recordVirtualNodes(ctx context.Context) error {
+	out, err := commandOutput(ctx, "helm", []string{"list", "--filter", "^" + virtualNodesRelease + "$", "--output", "json"})
+	if err != nil {
+		return fmt.Errorf("%s: failed to list helm releases: %v", task.ID(), err)
+	}
+	revision, err := releaseRevision(out, virtualNodesRelease)
+	if err != nil {
+		return fmt.Errorf("%s: failed to parse helm releases: %v", task.ID(), err)
+	}
+
+	if len(revision) == 0 {
+		task.record(stageObjects, "uninstall virtual nodes", func(ctx context.Context) error {
+			return runCommand(ctx, "helm", []string{"uninstall", virtualNodesRelease, "--wait"})
+		})
+		return nil
+	}
+
+	task.record(stageObjects, "roll back virtual nodes to revision "+revision, func(ctx context.Context) error {
+		return runCommand(ctx, "helm", []string{"rollback", virtualNodesRelease, revision, "--wait"})
+	})
+	return nil
+}
+
+// releaseRevision returns the revision of the release from the output of "helm list --output json",
+// or an empty string if the release is not listed
+func releaseRevision(out, name string) (string, error) {
+	var releases []struct {
+		Name     string `json:"name"`
+		Revision string `json:"revision"`
+	}
+	if err := json.Unmarshal([]byte(out), &releases); err != nil {
+		return "", err
+	}
+
+	for _, release := range releases {
+		if release.Name == name {
+			return release.Revision, nil
+		}
+	}
+	return "", nil
+}
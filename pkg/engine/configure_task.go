@@ -21,8 +21,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"os/exec"
-	"strings"
 	"sync"
 	"time"
 
@@ -31,7 +31,7 @@ import (
 	schedulingv1 "k8s.io/api/scheduling/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	log "k8s.io/klog/v2"
 
@@ -42,7 +42,15 @@ type ConfigureTask struct {
 	BaseTask
 	configureTaskParams
 
-	client *kubernetes.Clientset
+	client    *kubernetes.Clientset
+	dynClient *dynamic.DynamicClient
+	accessor  ObjInfoAccessor
+	recoverer Recoverer
+	rnd       *rand.Rand
+	// snapshot: the steps restoring the cluster state, if Restore is set
+	snapshot *configSnapshot
+	// objects: the objects of the sections configured with the dynamic client
+	objects []*configObject
 }
 
 type configureTaskParams struct {
@@ -51,6 +59,15 @@ type configureTaskParams struct {
 	ConfigMaps         []configmap          `yaml:"configmaps"`
 	PriorityClasses    []priorityClass      `yaml:"priorityClasses"`
 	DeploymentRestarts []*deploymentRestart `yaml:"deploymentRestarts"`
+	ServiceAccounts    []serviceAccount     `yaml:"serviceAccounts,omitempty"`
+	RoleBindings       []roleBinding        `yaml:"roleBindings,omitempty"`
+	ResourceQuotas     []resourceQuota      `yaml:"resourceQuotas,omitempty"`
+	LimitRanges        []limitRange         `yaml:"limitRanges,omitempty"`
+	NodeTaints         []nodeTaint          `yaml:"nodeTaints,omitempty"`
+	Manifests          []manifest           `yaml:"manifests,omitempty"`
+	// Restore: restore the cluster state changed by the task, when the workflow ends
+	// or when requested by Restore task; the created objects are deleted, and the updated or deleted ones are reinstated
+	Restore bool `yaml:"restore,omitempty"`
 
 	Timeout time.Duration `yaml:"timeout"`
 }
@@ -61,11 +78,15 @@ type virtualNode struct {
 	Annotations map[string]string   `yaml:"annotations,omitempty" json:"annotations,omitempty"`
 	Labels      map[string]string   `yaml:"labels,omitempty" json:"labels,omitempty"`
 	Conditions  []map[string]string `yaml:"conditions,omitempty" json:"conditions,omitempty"`
+	// Suffixes: node name suffixes derived from the workflow seed
+	Suffixes []string `yaml:"-" json:"suffixes,omitempty"`
 }
 
 type namespace struct {
 	Name string `yaml:"name"`
-	Op   string `yaml:"op"`
+	// Labels: labels merged into the namespace, e.g. for the namespace selectors of queues
+	Labels map[string]string `yaml:"labels,omitempty"`
+	Op     string            `yaml:"op"`
 }
 
 type configmap struct {
@@ -81,13 +102,49 @@ type priorityClass struct {
 	Op    string `yaml:"op"`
 }
 
+// deploymentRestart restarts the workload controllers selected by name or labels
 type deploymentRestart struct {
+	// Kind: "Deployment" (default), "StatefulSet" or "DaemonSet"
+	Kind      string            `yaml:"kind,omitempty" json:"kind,omitempty"`
 	Name      string            `yaml:"name,omitempty"`
 	Namespace string            `yaml:"namespace"`
 	Labels    map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
 }
 
-func newConfigureTask(client *kubernetes.Clientset, cfg *config.Task) (*ConfigureTask, error) {
+// kind returns the kind of the restarted workload controllers
+func (dr *deploymentRestart) kind() string {
+	if len(dr.Kind) == 0 {
+		return KindDeployment
+	}
+	return dr.Kind
+}
+
+// validate validates the workload restart
+func (dr *deploymentRestart) validate() error {
+	switch dr.kind() {
+	case KindDeployment, KindStatefulSet, KindDaemonSet:
+		// nop
+	default:
+		return fmt.Errorf("invalid restart kind %s; supported: %s, %s, %s", dr.Kind, KindDeployment, KindStatefulSet, KindDaemonSet)
+	}
+	if len(dr.Namespace) == 0 {
+		return fmt.Errorf("must provide namespace when restarting deployment")
+	}
+	if (len(dr.Name) != 0 && len(dr.Labels) != 0) || (len(dr.Name) == 0 && len(dr.Labels) == 0) {
+		return fmt.Errorf("must provide either name or labels when restarting deployment")
+	}
+	if len(dr.Name) == 0 {
+		for key := range dr.Labels {
+			if len(key) == 0 {
+				return fmt.Errorf("must provide non-empty label name when restarting deployment")
+			}
+		}
+	}
+	return nil
+}
+
+func newConfigureTask(client *kubernetes.Clientset, dynClient *dynamic.DynamicClient, accessor ObjInfoAccessor,
+	recoverer Recoverer, rnd *rand.Rand, templates map[string]string, cfg *config.Task) (*ConfigureTask, error) {
 	if client == nil {
 		return nil, fmt.Errorf("%s/%s: Kubernetes client is not set", cfg.Type, cfg.ID)
 	}
@@ -97,10 +154,14 @@ func newConfigureTask(client *kubernetes.Clientset, cfg *config.Task) (*Configur
 			taskType: TaskConfigure,
 			taskID:   cfg.ID,
 		},
-		client: client,
+		client:    client,
+		dynClient: dynClient,
+		accessor:  accessor,
+		recoverer: recoverer,
+		rnd:       rnd,
 	}
 
-	if err := task.validate(cfg.Params); err != nil {
+	if err := task.validate(cfg.Params, templates); err != nil {
 		return nil, err
 	}
 
@@ -108,7 +169,7 @@ func newConfigureTask(client *kubernetes.Clientset, cfg *config.Task) (*Configur
 }
 
 // validate initializes and validates parameters for ConfigureTask
-func (task *ConfigureTask) validate(params map[string]interface{}) error {
+func (task *ConfigureTask) validate(params map[string]interface{}, templates map[string]string) error {
 	data, err := yaml.Marshal(params)
 	if err != nil {
 		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
@@ -149,21 +210,15 @@ func (task *ConfigureTask) validate(params map[string]interface{}) error {
 	}
 
 	for _, dr := range task.DeploymentRestarts {
-		if len(dr.Namespace) == 0 {
-			return fmt.Errorf("%s: must provide namespace when restarting deployment", task.ID())
-		}
-		if (len(dr.Name) != 0 && len(dr.Labels) != 0) || (len(dr.Name) == 0 && len(dr.Labels) == 0) {
-			return fmt.Errorf("%s: must provide either name or labels when restarting deployment", task.ID())
-		}
-		if len(dr.Name) == 0 {
-			for key := range dr.Labels {
-				if len(key) == 0 {
-					return fmt.Errorf("%s: must provide non-empty label name when restarting deployment", task.ID())
-				}
-			}
+		if err = dr.validate(); err != nil {
+			return fmt.Errorf("%s: %v", task.ID(), err)
 		}
 	}
 
+	if err = task.validateResources(templates); err != nil {
+		return err
+	}
+
 	if task.Timeout == 0 {
 		return fmt.Errorf("%s: missing parameter 'timeout'", task.ID())
 	}
@@ -176,6 +231,20 @@ func (task *ConfigureTask) Exec(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, task.Timeout)
 	defer cancel()
 
+	// the recovery is registered before the changes, so that partial changes are restored as well
+	if task.Restore {
+		task.snapshot = &configSnapshot{}
+		task.recoverer.AddRecovery(task.taskID, NewRecovery(task.ID(), func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, task.Timeout)
+			defer cancel()
+			return task.snapshot.restore(ctx)
+		}))
+	}
+
+	// the created namespaces, priority classes, virtual nodes and manifest objects are labeled, so that they can be cleaned up
+	labels := task.trackingLabels(runIDFrom(ctx))
+	labels[LabelConfigured] = "true"
+
 	errs := make(chan error)
 	var wg sync.WaitGroup
 	wg.Add(4)
@@ -187,12 +256,12 @@ func (task *ConfigureTask) Exec(ctx context.Context) error {
 
 	go func() {
 		defer wg.Done()
-		errs <- task.updateNamespaces(ctx)
+		errs <- task.updateNamespaces(ctx, labels)
 	}()
 
 	go func() {
 		defer wg.Done()
-		errs <- task.updatePriorityClasses(ctx)
+		errs <- task.updatePriorityClasses(ctx, labels)
 	}()
 
 	go func() {
@@ -202,7 +271,7 @@ func (task *ConfigureTask) Exec(ctx context.Context) error {
 
 	go func() {
 		defer wg.Done()
-		errs <- task.updateVirtualNodes(ctx)
+		errs <- task.updateVirtualNodes(ctx, labels)
 	}()
 
 	var err error
@@ -217,8 +286,19 @@ func (task *ConfigureTask) Exec(ctx context.Context) error {
 		return err
 	}
 
+	// the namespaced resources are configured after the namespaces are created
+	if err = task.updateNamespaceLabels(ctx); err != nil {
+		return err
+	}
+	if err = task.updateObjects(ctx, labels); err != nil {
+		return err
+	}
+	if err = task.updateNodeTaints(ctx); err != nil {
+		return err
+	}
+
 	for _, dr := range task.DeploymentRestarts {
-		if err = task.restartDeployment(ctx, dr); err != nil {
+		if err = restartWorkloads(ctx, task.client, dr); err != nil {
 			return err
 		}
 	}
@@ -226,7 +306,7 @@ func (task *ConfigureTask) Exec(ctx context.Context) error {
 	return nil
 }
 
-func (task *ConfigureTask) updateNamespaces(ctx context.Context) error {
+func (task *ConfigureTask) updateNamespaces(ctx context.Context, labels map[string]string) error {
 	for _, ns := range task.Namespaces {
 		log.Infof("%s namespace %s", ns.Op, ns.Name)
 		switch ns.Op {
@@ -235,30 +315,49 @@ func (task *ConfigureTask) updateNamespaces(ctx context.Context) error {
 			if err == nil {
 				log.Infof("Namespace %s already exist", ns.Name)
 			} else if errors.IsNotFound(err) {
-				ns := &corev1.Namespace{
+				newObj := &corev1.Namespace{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: ns.Name,
+						Name:   ns.Name,
+						Labels: labels,
 					},
 				}
-				_, err = task.client.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+				if _, err = task.client.CoreV1().Namespaces().Create(ctx, newObj, metav1.CreateOptions{}); err == nil {
+					task.record(stageNamespaces, "delete namespace "+ns.Name, func(ctx context.Context) error {
+						return ignoreNotFound(task.client.CoreV1().Namespaces().Delete(ctx, newObj.Name, metav1.DeleteOptions{}))
+					})
+				}
 			}
 			if err != nil {
 				return fmt.Errorf("%s: failed to create namespace %s: %v", task.ID(), ns.Name, err)
 			}
 
 		case OpDelete:
+			var curObj *corev1.Namespace
+			if task.snapshot != nil {
+				var err error
+				if curObj, err = task.client.CoreV1().Namespaces().Get(ctx, ns.Name, metav1.GetOptions{}); err != nil {
+					return fmt.Errorf("%s: failed to get namespace %s: %v", task.ID(), ns.Name, err)
+				}
+			}
 			err := task.client.CoreV1().Namespaces().Delete(ctx, ns.Name, metav1.DeleteOptions{})
 			if err != nil {
 				return fmt.Errorf("%s: failed to delete namespace %s: %v", task.ID(), ns.Name, err)
 			}
 			log.Infof("Namespace %s deleted", ns.Name)
+
+			if curObj != nil {
+				oldObj := &corev1.Namespace{ObjectMeta: restorableMeta(&curObj.ObjectMeta)}
+				task.record(stageNamespaces, "re-create namespace "+ns.Name, func(ctx context.Context) error {
+					return createNamespace(ctx, task.client, oldObj)
+				})
+			}
 		}
 	}
 
 	return nil
 }
 
-func (task *ConfigureTask) updatePriorityClasses(ctx context.Context) error {
+func (task *ConfigureTask) updatePriorityClasses(ctx context.Context, labels map[string]string) error {
 	for _, pc := range task.PriorityClasses {
 		log.Infof("%s PriorityClass %s", pc.Op, pc.Name)
 
@@ -277,22 +376,40 @@ func (task *ConfigureTask) updatePriorityClasses(ctx context.Context) error {
 					log.Infof("PriorityClass %s with value %d already exist", curObj.Name, curObj.Value)
 				} else {
 					log.Infof("Updating PriorityClass %s with value %d", newObj.Name, newObj.Value)
-					_, err = task.client.SchedulingV1().PriorityClasses().Update(ctx, newObj, metav1.UpdateOptions{})
+					if _, err = task.client.SchedulingV1().PriorityClasses().Update(ctx, newObj, metav1.UpdateOptions{}); err == nil {
+						task.recordPriorityClass(curObj)
+					}
 				}
 			} else if errors.IsNotFound(err) {
 				log.Infof("Creating PriorityClass %s with value %d", newObj.Name, newObj.Value)
-				_, err = task.client.SchedulingV1().PriorityClasses().Create(ctx, newObj, metav1.CreateOptions{})
+				newObj.Labels = labels
+				if _, err = task.client.SchedulingV1().PriorityClasses().Create(ctx, newObj, metav1.CreateOptions{}); err == nil {
+					task.record(stageObjects, "delete PriorityClass "+pc.Name, func(ctx context.Context) error {
+						return ignoreNotFound(task.client.SchedulingV1().PriorityClasses().Delete(ctx, newObj.Name, metav1.DeleteOptions{}))
+					})
+				}
 			}
 			if err != nil {
 				return fmt.Errorf("%s: failed to create PriorityClass %s: %v", task.ID(), pc.Name, err)
 			}
 
 		case OpDelete:
+			var curObj *schedulingv1.PriorityClass
+			if task.snapshot != nil {
+				var err error
+				if curObj, err = task.client.SchedulingV1().PriorityClasses().Get(ctx, pc.Name, metav1.GetOptions{}); err != nil {
+					return fmt.Errorf("%s: failed to get PriorityClass %s: %v", task.ID(), pc.Name, err)
+				}
+			}
 			err := task.client.SchedulingV1().PriorityClasses().Delete(ctx, pc.Name, metav1.DeleteOptions{})
 			if err != nil {
 				return fmt.Errorf("%s: failed to delete PriorityClass %s: %v", task.ID(), pc.Name, err)
 			}
 			log.Infof("PriorityClass %s deleted", pc.Name)
+
+			if curObj != nil {
+				task.recordPriorityClass(curObj)
+			}
 		}
 	}
 
@@ -312,13 +429,19 @@ func (task *ConfigureTask) updateConfigmaps(ctx context.Context) error {
 				},
 				Data: cm.Data,
 			}
-			_, err := task.client.CoreV1().ConfigMaps(cm.Namespace).Get(ctx, cm.Name, metav1.GetOptions{})
+			curObj, err := task.client.CoreV1().ConfigMaps(cm.Namespace).Get(ctx, cm.Name, metav1.GetOptions{})
 			if err == nil {
 				op = "update"
-				_, err = task.client.CoreV1().ConfigMaps(cm.Namespace).Update(ctx, cmap, metav1.UpdateOptions{})
+				if _, err = task.client.CoreV1().ConfigMaps(cm.Namespace).Update(ctx, cmap, metav1.UpdateOptions{}); err == nil {
+					task.recordConfigmap(curObj)
+				}
 			} else if errors.IsNotFound(err) {
 				op = "create"
-				_, err = task.client.CoreV1().ConfigMaps(cm.Namespace).Create(ctx, cmap, metav1.CreateOptions{})
+				if _, err = task.client.CoreV1().ConfigMaps(cm.Namespace).Create(ctx, cmap, metav1.CreateOptions{}); err == nil {
+					task.record(stageObjects, "delete configmap "+objectKey(cm.Namespace, cm.Name), func(ctx context.Context) error {
+						return ignoreNotFound(task.client.CoreV1().ConfigMaps(cmap.Namespace).Delete(ctx, cmap.Name, metav1.DeleteOptions{}))
+					})
+				}
 			}
 			if err != nil {
 				return fmt.Errorf("%s: failed to %s configmap %s: %v", task.ID(), op, cm.Name, err)
@@ -326,9 +449,11 @@ func (task *ConfigureTask) updateConfigmaps(ctx context.Context) error {
 			log.Infof("Configmap %s %sd", cm.Name, op)
 
 		case OpDelete:
-			_, err := task.client.CoreV1().ConfigMaps(cm.Namespace).Get(ctx, cm.Name, metav1.GetOptions{})
+			curObj, err := task.client.CoreV1().ConfigMaps(cm.Namespace).Get(ctx, cm.Name, metav1.GetOptions{})
 			if err == nil {
-				err = task.client.CoreV1().ConfigMaps(cm.Namespace).Delete(ctx, cm.Name, metav1.DeleteOptions{})
+				if err = task.client.CoreV1().ConfigMaps(cm.Namespace).Delete(ctx, cm.Name, metav1.DeleteOptions{}); err == nil {
+					task.recordConfigmap(curObj)
+				}
 			} else if errors.IsNotFound(err) {
 				log.V(4).Infof("Configmap %s does not exist; nothing to delete", cm.Name)
 				err = nil
@@ -343,73 +468,25 @@ func (task *ConfigureTask) updateConfigmaps(ctx context.Context) error {
 	return nil
 }
 
-func (task *ConfigureTask) restartDeployment(ctx context.Context, dr *deploymentRestart) error {
-	dClient := task.client.AppsV1().Deployments(dr.Namespace)
-
-	dName := dr.Name
-	if len(dName) == 0 {
-		labels := make([]string, 0, len(dr.Labels))
-		for key, val := range dr.Labels {
-			labels = append(labels, key+"="+val)
-		}
-		lbl := strings.Join(labels, ",")
-
-		list, err := dClient.List(ctx, metav1.ListOptions{LabelSelector: lbl})
-		if err != nil {
-			log.InfoS("Warning: skipping restart of deployment", "labels", lbl, "error", err.Error())
-			return nil
-		}
-
-		if len(list.Items) == 0 {
-			log.InfoS("Warning: no deployment to restart", "labels", lbl)
-			return nil
-		}
-
-		if len(list.Items) != 1 {
-			return fmt.Errorf("expected 1 deployment with labels %s, not %d", lbl, len(list.Items))
-		}
+// virtualNodesRelease is the name of the helm release of the virtual nodes
+const virtualNodesRelease = "virtual-nodes"
 
-		dName = list.Items[0].Name
-	}
-	log.Infof("Restarting deployment %s", dName)
+// virtualNodesChart is the virtual-nodes chart in the knavigator helm repo
+const virtualNodesChart = "knavigator/virtual-nodes"
 
-	update := fmt.Sprintf(`{"spec": {"template": {"metadata": {"annotations": {"kubectl.kubernetes.io/restartedAt": "%s"}}}}}`,
-		time.Now().Format("2006-01-02T15:04:05-07:00"))
+// virtualNodesChartVersion is the version of the virtual-nodes chart that supports the node name suffixes;
+// it must match the version in charts/virtual-nodes/Chart.yaml.
+// If the version is not published yet, the latest chart is installed instead.
+const virtualNodesChartVersion = "0.3.0"
 
-	_, err := dClient.Patch(ctx, dName, k8stypes.StrategicMergePatchType, []byte(update), metav1.PatchOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to update deployment %s: %s", dName, err.Error())
-	}
-
-	delay := 5 * time.Second
-	timer := time.NewTimer(delay)
-	defer timer.Stop()
-	for {
-		select {
-		case <-timer.C:
-			d, err := dClient.Get(ctx, dName, metav1.GetOptions{})
-			if err != nil {
-				log.Errorf("failed to get status for deployment %s : %v", dName, err)
-			} else if d.Status.UnavailableReplicas != 0 {
-				log.V(4).Infof("Restarting deployment %s: %d unavailable replicas", dName, d.Status.UnavailableReplicas)
-			} else {
-				log.Infof("Restarted deployment %s", dName)
-				return nil
-			}
-			timer.Reset(delay)
-
-		case <-ctx.Done():
-			return ctx.Err()
-		}
-	}
-}
-
-func (task *ConfigureTask) updateVirtualNodes(ctx context.Context) error {
+func (task *ConfigureTask) updateVirtualNodes(ctx context.Context, labels map[string]string) error {
 	if len(task.Nodes) == 0 {
 		return nil
 	}
 
-	nodeExpr, err := nodes2json(task.Nodes)
+	setNodeSuffixes(task.Nodes, task.rnd)
+
+	nodeExpr, err := nodes2json(withNodeLabels(task.Nodes, labels))
 	if err != nil {
 		return err
 	}
@@ -423,15 +500,94 @@ func (task *ConfigureTask) updateVirtualNodes(ctx context.Context) error {
 		return err
 	}
 
+	if task.snapshot != nil {
+		if err = task.recordVirtualNodes(ctx); err != nil {
+			return err
+		}
+	}
+
 	// upgrade helm chart
-	args = []string{"upgrade", "--install", "virtual-nodes", "knavigator/virtual-nodes",
-		"--wait", "--set-json", nodeExpr}
+	args = []string{"upgrade", "--install", virtualNodesRelease, virtualNodesChart, "--wait", "--set-json", nodeExpr}
+
+	available, err := chartVersionAvailable(ctx, virtualNodesChart, virtualNodesChartVersion)
+	if err != nil {
+		return err
+	}
+	if available {
+		args = append(args, "--version", virtualNodesChartVersion)
+	} else {
+		log.Warningf("Chart %s version %s is not available; installing the latest version, virtual node names may not be reproducible",
+			virtualNodesChart, virtualNodesChartVersion)
+	}
 
 	log.V(4).Infof("Updating nodes with %v", append([]string{"helm"}, args...))
 
 	return runCommand(ctx, "helm", args)
 }
 
+// chartVersionAvailable returns true if the helm repo has the given version of the chart
+func chartVersionAvailable(ctx context.Context, chart, version string) (bool, error) {
+	out, err := commandOutput(ctx, "helm", []string{"search", "repo", chart, "--version", version, "-o", "json"})
+	if err != nil {
+		return false, err
+	}
+	return hasChart(out, chart)
+}
+
+// hasChart returns true if the output of 'helm search repo -o json' contains the chart
+func hasChart(out, chart string) (bool, error) {
+	var charts []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(out), &charts); err != nil {
+		return false, err
+	}
+
+	for _, c := range charts {
+		if c.Name == chart {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// setNodeSuffixes generates unique random node name suffixes,
+// so that the node names are reproducible with the same workflow seed
+func setNodeSuffixes(nodes []virtualNode, rnd *rand.Rand) {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	used := make(map[string]bool)
+	for i := range nodes {
+		nodes[i].Suffixes = make([]string, 0, nodes[i].Count)
+		for len(nodes[i].Suffixes) < nodes[i].Count {
+			b := make([]byte, 6)
+			for j := range b {
+				b[j] = chars[rnd.Intn(len(chars))]
+			}
+			if suffix := string(b); !used[suffix] {
+				used[suffix] = true
+				nodes[i].Suffixes = append(nodes[i].Suffixes, suffix)
+			}
+		}
+	}
+}
+
+// withNodeLabels returns a copy of the virtual nodes with the additional labels
+func withNodeLabels(nodes []virtualNode, labels map[string]string) []virtualNode {
+	out := make([]virtualNode, len(nodes))
+	for i, node := range nodes {
+		node.Labels = make(map[string]string, len(nodes[i].Labels)+len(labels))
+		for key, val := range nodes[i].Labels {
+			node.Labels[key] = val
+		}
+		for key, val := range labels {
+			node.Labels[key] = val
+		}
+		out[i] = node
+	}
+	return out
+}
+
 func nodes2json(nodes []virtualNode) (string, error) {
 	data, err := json.Marshal(nodes)
 	if err != nil {
@@ -441,6 +597,12 @@ func nodes2json(nodes []virtualNode) (string, error) {
 }
 
 func runCommand(ctx context.Context, exe string, args []string) error {
+	_, err := commandOutput(ctx, exe, args)
+	return err
+}
+
+// commandOutput runs the command and returns its standard output
+func commandOutput(ctx context.Context, exe string, args []string) (string, error) {
 	command := exec.CommandContext(ctx, exe, args...)
 
 	var stdout, stderr bytes.Buffer
@@ -449,10 +611,10 @@ func runCommand(ctx context.Context, exe string, args []string) error {
 
 	if err := command.Run(); err != nil {
 		log.Errorf("failed to run command: err:%v stdout:%s stderr:%s", err, stdout.String(), stderr.String())
-		return err
+		return "", err
 	}
 
 	log.V(4).Info(stdout.String())
 
-	return nil
+	return stdout.String(), nil
 }
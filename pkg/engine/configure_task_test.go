@@ -17,10 +17,24 @@
 package engine
 
 import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
 
 	"github.com/NVIDIA/knavigator/pkg/config"
 )
@@ -149,6 +163,90 @@ func TestNewConfigureTask(t *testing.T) {
 			},
 			err: "Configure/configure: must provide non-empty label name when restarting deployment",
 		},
+		{
+			name:       "Case 7d1: Invalid restart kind",
+			simClients: true,
+			params: map[string]interface{}{
+				"timeout":            "1m",
+				"deploymentRestarts": []interface{}{map[string]interface{}{"kind": "Job", "namespace": "ns", "name": "job"}},
+			},
+			err: "Configure/configure: invalid restart kind Job; supported: Deployment, StatefulSet, DaemonSet",
+		},
+		{
+			name:       "Case 7e: Invalid ResourceQuota op",
+			simClients: true,
+			params: map[string]interface{}{
+				"timeout":        "1m",
+				"resourceQuotas": []interface{}{map[string]interface{}{"name": "quota", "namespace": "ns", "op": "BAD"}},
+			},
+			err: "Configure/configure: invalid ResourceQuota operation BAD; supported: create, delete",
+		},
+		{
+			name:       "Case 7f: Missing ResourceQuota spec",
+			simClients: true,
+			params: map[string]interface{}{
+				"timeout":        "1m",
+				"resourceQuotas": []interface{}{map[string]interface{}{"name": "quota", "namespace": "ns", "op": "create"}},
+			},
+			err: "Configure/configure: must provide spec when creating ResourceQuota quota",
+		},
+		{
+			name:       "Case 7g: Missing LimitRange namespace",
+			simClients: true,
+			params: map[string]interface{}{
+				"timeout":     "1m",
+				"limitRanges": []interface{}{map[string]interface{}{"name": "limits", "op": "delete"}},
+			},
+			err: "Configure/configure: must provide name and namespace of LimitRange",
+		},
+		{
+			name:       "Case 7h: Invalid RoleBinding subject",
+			simClients: true,
+			params: map[string]interface{}{
+				"timeout": "1m",
+				"roleBindings": []interface{}{map[string]interface{}{
+					"name":     "admin",
+					"roleRef":  map[string]interface{}{"kind": "ClusterRole", "name": "admin"},
+					"subjects": []interface{}{map[string]interface{}{"kind": "BAD", "name": "user"}},
+					"op":       "create",
+				}},
+			},
+			err: `Configure/configure: ClusterRoleBinding admin: invalid subject kind "BAD"`,
+		},
+		{
+			name:       "Case 7i: Missing taint effect",
+			simClients: true,
+			params: map[string]interface{}{
+				"timeout": "1m",
+				"nodeTaints": []interface{}{map[string]interface{}{
+					"names":  []interface{}{"node1"},
+					"taints": []interface{}{map[string]interface{}{"key": "dedicated"}},
+					"op":     "create",
+				}},
+			},
+			err: "Configure/configure: must provide effect of taint dedicated",
+		},
+		{
+			name:       "Case 7j: Missing node taint selectors",
+			simClients: true,
+			params: map[string]interface{}{
+				"timeout": "1m",
+				"nodeTaints": []interface{}{map[string]interface{}{
+					"taints": []interface{}{map[string]interface{}{"key": "dedicated"}},
+					"op":     "delete",
+				}},
+			},
+			err: "Configure/configure: must provide node names and/or labels for node taints",
+		},
+		{
+			name:       "Case 7k: Invalid manifest",
+			simClients: true,
+			params: map[string]interface{}{
+				"timeout":   "1m",
+				"manifests": []interface{}{map[string]interface{}{"template": "kind: ConfigMap\nmetadata: {}\n", "op": "create"}},
+			},
+			err: "Configure/configure: manifest objects must have apiVersion, kind and metadata.name",
+		},
 		{
 			name:       "Case 8: Valid parameters with default",
 			simClients: true,
@@ -161,7 +259,9 @@ func TestNewConfigureTask(t *testing.T) {
 				configureTaskParams: configureTaskParams{
 					Timeout: time.Duration(time.Minute),
 				},
-				client: testK8sClient,
+				client:    testK8sClient,
+				dynClient: testDynamicClient,
+				objects:   []*configObject{},
 			},
 		},
 		{
@@ -251,7 +351,132 @@ func TestNewConfigureTask(t *testing.T) {
 						},
 					},
 				},
-				client: testK8sClient,
+				client:    testK8sClient,
+				dynClient: testDynamicClient,
+				objects:   []*configObject{},
+			},
+		},
+		{
+			name:       "Case 10: Valid resource sections",
+			simClients: true,
+			params: map[string]interface{}{
+				"timeout": "1m",
+				"namespaces": []interface{}{
+					map[string]interface{}{"name": "team-a", "op": "create", "labels": map[string]interface{}{"team": "a"}},
+				},
+				"serviceAccounts": []interface{}{
+					map[string]interface{}{"name": "runner", "namespace": "team-a", "op": "create"},
+				},
+				"roleBindings": []interface{}{
+					map[string]interface{}{
+						"name":      "runner",
+						"namespace": "team-a",
+						"roleRef":   map[string]interface{}{"kind": "ClusterRole", "name": "edit"},
+						"subjects":  []interface{}{map[string]interface{}{"kind": "ServiceAccount", "name": "runner", "namespace": "team-a"}},
+						"op":        "create",
+					},
+				},
+				"resourceQuotas": []interface{}{
+					map[string]interface{}{
+						"name":      "quota",
+						"namespace": "team-a",
+						"spec":      map[string]interface{}{"hard": map[string]interface{}{"cpu": "10", "pods": 20}},
+						"op":        "create",
+					},
+				},
+				"limitRanges": []interface{}{
+					map[string]interface{}{"name": "limits", "namespace": "team-a", "op": "delete"},
+				},
+				"nodeTaints": []interface{}{
+					map[string]interface{}{
+						"labels": map[string]interface{}{"pool": "gpu"},
+						"taints": []interface{}{map[string]interface{}{"key": "nvidia.com/gpu", "effect": "NoSchedule"}},
+						"op":     "create",
+					},
+				},
+				"manifests": []interface{}{
+					map[string]interface{}{"template": "apiVersion: v1\nkind: Secret\nmetadata:\n  name: token\n  namespace: team-a\n", "op": "delete"},
+				},
+			},
+			task: &ConfigureTask{
+				BaseTask: BaseTask{
+					taskType: TaskConfigure,
+					taskID:   taskID,
+				},
+				configureTaskParams: configureTaskParams{
+					Timeout: time.Duration(time.Minute),
+					Namespaces: []namespace{
+						{Name: "team-a", Labels: map[string]string{"team": "a"}, Op: OpCreate},
+					},
+					ServiceAccounts: []serviceAccount{
+						{Name: "runner", Namespace: "team-a", Op: OpCreate},
+					},
+					RoleBindings: []roleBinding{
+						{
+							Name:      "runner",
+							Namespace: "team-a",
+							RoleRef:   roleRef{Kind: "ClusterRole", Name: "edit"},
+							Subjects:  []rbacSubject{{Kind: "ServiceAccount", Name: "runner", Namespace: "team-a"}},
+							Op:        OpCreate,
+						},
+					},
+					ResourceQuotas: []resourceQuota{
+						{
+							Name:      "quota",
+							Namespace: "team-a",
+							Spec:      map[string]interface{}{"hard": map[string]interface{}{"cpu": "10", "pods": 20}},
+							Op:        OpCreate,
+						},
+					},
+					LimitRanges: []limitRange{
+						{Name: "limits", Namespace: "team-a", Op: OpDelete},
+					},
+					NodeTaints: []nodeTaint{
+						{
+							Labels: map[string]string{"pool": "gpu"},
+							Taints: []taint{{Key: "nvidia.com/gpu", Effect: "NoSchedule"}},
+							Op:     OpCreate,
+						},
+					},
+					Manifests: []manifest{
+						{Template: "apiVersion: v1\nkind: Secret\nmetadata:\n  name: token\n  namespace: team-a\n", Op: OpDelete},
+					},
+				},
+				client:    testK8sClient,
+				dynClient: testDynamicClient,
+				objects: []*configObject{
+					{
+						obj: newConfigObject("v1", "ServiceAccount", "runner", "team-a", nil),
+						op:  OpCreate,
+					},
+					{
+						obj: newConfigObject("rbac.authorization.k8s.io/v1", "RoleBinding", "runner", "team-a", map[string]interface{}{
+							"roleRef": map[string]interface{}{
+								"apiGroup": "rbac.authorization.k8s.io",
+								"kind":     "ClusterRole",
+								"name":     "edit",
+							},
+							"subjects": []interface{}{
+								map[string]interface{}{"kind": "ServiceAccount", "name": "runner", "namespace": "team-a"},
+							},
+						}),
+						op: OpCreate,
+					},
+					{
+						obj: newConfigObject("v1", "ResourceQuota", "quota", "team-a", map[string]interface{}{
+							"spec": map[string]interface{}{"hard": map[string]interface{}{"cpu": "10", "pods": int64(20)}},
+						}),
+						op: OpCreate,
+					},
+					{
+						obj: newConfigObject("v1", "LimitRange", "limits", "team-a", nil),
+						op:  OpDelete,
+					},
+					{
+						obj: newConfigObject("v1", "Secret", "token", "team-a", nil),
+						op:  OpDelete,
+					},
+				},
 			},
 		},
 	}
@@ -271,6 +496,11 @@ func TestNewConfigureTask(t *testing.T) {
 			} else {
 				require.NoError(t, err)
 				require.NotNil(t, tc.task)
+				// the random number generator is derived from the workflow seed
+				require.NotNil(t, task.(*ConfigureTask).rnd)
+				tc.task.rnd = task.(*ConfigureTask).rnd
+				tc.task.accessor = eng
+				tc.task.recoverer = eng
 				require.Equal(t, tc.task, task)
 			}
 		})
@@ -302,3 +532,324 @@ func TestNodes2JSON(t *testing.T) {
 		})
 	}
 }
+
+func TestSetNodeSuffixes(t *testing.T) {
+	suffixes := func() [][]string {
+		nodes := []virtualNode{{Type: "dgxa100.80g", Count: 4}, {Type: "cpu-tiny", Count: 2}}
+		setNodeSuffixes(nodes, rand.New(rand.NewSource(1)))
+		return [][]string{nodes[0].Suffixes, nodes[1].Suffixes}
+	}
+
+	first := suffixes()
+	require.Len(t, first[0], 4)
+	require.Len(t, first[1], 2)
+	for _, s := range append(first[0], first[1]...) {
+		require.Regexp(t, "^[a-z0-9]{6}$", s)
+	}
+
+	// the same seed results in the same node names
+	require.Equal(t, first, suffixes())
+
+	out, err := nodes2json([]virtualNode{{Type: "cpu-tiny", Count: 1, Suffixes: []string{"abc123"}}})
+	require.NoError(t, err)
+	require.Equal(t, `nodes=[{"type":"cpu-tiny","count":1,"suffixes":["abc123"]}]`, out)
+}
+
+func TestWithNodeLabels(t *testing.T) {
+	nodes := []virtualNode{{Type: "cpu-tiny", Count: 1, Labels: map[string]string{"zone": "a"}}, {Type: "cpu-tiny", Count: 2}}
+	labels := map[string]string{LabelRunID: "run1"}
+
+	out := withNodeLabels(nodes, labels)
+	require.Equal(t, map[string]string{"zone": "a", LabelRunID: "run1"}, out[0].Labels)
+	require.Equal(t, map[string]string{LabelRunID: "run1"}, out[1].Labels)
+	// the task parameters are not modified
+	require.Equal(t, map[string]string{"zone": "a"}, nodes[0].Labels)
+	require.Nil(t, nodes[1].Labels)
+}
+
+func TestConfigSnapshot(t *testing.T) {
+	steps := []string{}
+	step := func(desc string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			steps = append(steps, desc)
+			return err
+		}
+	}
+
+	s := &configSnapshot{}
+	s.add(stageNamespaces, "delete namespace ns1", step("ns1", nil))
+	s.add(stageObjects, "delete configmap ns1/cm1", step("cm1", nil))
+	s.add(stageObjects, "restore PriorityClass pc1", step("pc1", fmt.Errorf("conflict")))
+	s.add(stageNamespaces, "delete namespace ns2", step("ns2", nil))
+
+	// the objects are restored before the namespaces, in the reverse order; the failures don't stop the restore
+	require.EqualError(t, s.restore(context.Background()), "failed to restore 1 objects: restore PriorityClass pc1: conflict")
+	require.Equal(t, []string{"pc1", "cm1", "ns2", "ns1"}, steps)
+}
+
+func TestApplyTaints(t *testing.T) {
+	nodeTaints := []corev1.Taint{
+		{Key: "dedicated", Value: "a", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "dedicated", Value: "a", Effect: corev1.TaintEffectNoExecute},
+		{Key: "other", Effect: corev1.TaintEffectNoSchedule},
+	}
+
+	testCases := []struct {
+		name   string
+		taints []taint
+		op     string
+		exp    []corev1.Taint
+	}{
+		{
+			name:   "Case 1: replace taint with the same key and effect",
+			taints: []taint{{Key: "dedicated", Value: "b", Effect: "NoSchedule"}},
+			op:     OpCreate,
+			exp: []corev1.Taint{
+				{Key: "dedicated", Value: "a", Effect: corev1.TaintEffectNoExecute},
+				{Key: "other", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "dedicated", Value: "b", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+		{
+			name:   "Case 2: remove taints by key",
+			taints: []taint{{Key: "dedicated"}},
+			op:     OpDelete,
+			exp:    []corev1.Taint{{Key: "other", Effect: corev1.TaintEffectNoSchedule}},
+		},
+		{
+			name:   "Case 3: remove taint by key and effect",
+			taints: []taint{{Key: "dedicated", Effect: "NoExecute"}},
+			op:     OpDelete,
+			exp: []corev1.Taint{
+				{Key: "dedicated", Value: "a", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "other", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, applyTaints(nodeTaints, tc.taints, tc.op))
+		})
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	data := "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: sa\n  namespace: ns\n---\n" +
+		"apiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRole\nmetadata:\n  name: role\n"
+
+	objs, err := loadManifest("rbac.yaml", map[string]string{"rbac.yaml": data})
+	require.NoError(t, err)
+	require.Len(t, objs, 2)
+	require.Equal(t, "ServiceAccount", objs[0].GetKind())
+	require.Equal(t, "ns", objs[0].GetNamespace())
+	require.Equal(t, "ClusterRole", objs[1].GetKind())
+
+	_, err = loadManifest("/does/not/exist", nil)
+	require.EqualError(t, err, "failed to read manifest: open /does/not/exist: no such file or directory")
+}
+
+func TestRolloutStatus(t *testing.T) {
+	replicas, partition := int32(2), int32(1)
+	testCases := []struct {
+		name   string
+		obj    runtime.Object
+		done   bool
+		status string
+	}{
+		{
+			name: "Case 1: Deployment generation not observed",
+			obj: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 1},
+			},
+			status: "waiting for the rollout to be observed",
+		},
+		{
+			name: "Case 2: Deployment with old replicas",
+			obj: &appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: &replicas},
+				Status: appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 2, ReadyReplicas: 2},
+			},
+			status: "1 old replicas pending termination",
+		},
+		{
+			name: "Case 3a: Deployment replicas ready but not available",
+			obj: &appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: &replicas},
+				Status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2, AvailableReplicas: 1},
+			},
+			status: "1 out of 2 updated replicas available",
+		},
+		{
+			name: "Case 3b: Deployment complete",
+			obj: &appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: &replicas},
+				Status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2, AvailableReplicas: 2},
+			},
+			done:   true,
+			status: "complete",
+		},
+		{
+			name: "Case 4a: StatefulSet not updated",
+			obj: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					Replicas:       &replicas,
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType},
+				},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 2, UpdatedReplicas: 1, CurrentRevision: "a", UpdateRevision: "b"},
+			},
+			status: "1 out of 2 replicas updated",
+		},
+		{
+			name: "Case 4b: StatefulSet partitioned rollout in progress",
+			obj: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: &replicas,
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+						Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+						RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+					},
+				},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 2, UpdatedReplicas: 0, CurrentRevision: "a", UpdateRevision: "b"},
+			},
+			status: "0 out of 1 partitioned replicas updated",
+		},
+		{
+			name: "Case 4c: StatefulSet partitioned rollout complete",
+			obj: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: &replicas,
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+						Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+						RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+					},
+				},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 2, UpdatedReplicas: 1, CurrentRevision: "a", UpdateRevision: "b"},
+			},
+			done:   true,
+			status: "complete",
+		},
+		{
+			name: "Case 5: StatefulSet complete",
+			obj: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					Replicas:       &replicas,
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType},
+				},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 2, UpdatedReplicas: 2, CurrentRevision: "b", UpdateRevision: "b"},
+			},
+			done:   true,
+			status: "complete",
+		},
+		{
+			name: "Case 6: DaemonSet pods not ready",
+			obj: &appsv1.DaemonSet{
+				Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberReady: 2},
+			},
+			status: "2 out of 3 pods ready",
+		},
+		{
+			name: "Case 7: DaemonSet complete",
+			obj: &appsv1.DaemonSet{
+				Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberReady: 3},
+			},
+			done:   true,
+			status: "complete",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			done, status := rolloutStatus(tc.obj)
+			require.Equal(t, tc.done, done)
+			require.Equal(t, tc.status, status)
+		})
+	}
+}
+
+func TestVirtualNodesChartVersion(t *testing.T) {
+	data, err := os.ReadFile("../../charts/virtual-nodes/Chart.yaml")
+	require.NoError(t, err)
+
+	var chart struct {
+		Version string `yaml:"version"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &chart))
+	require.Equal(t, chart.Version, virtualNodesChartVersion)
+}
+
+func TestHasChart(t *testing.T) {
+	ok, err := hasChart(`[{"name":"knavigator/virtual-nodes","version":"0.3.0","app_version":"1.0.0"}]`, "knavigator/virtual-nodes")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = hasChart("[]", "knavigator/virtual-nodes")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = hasChart("Error: no repositories configured", "knavigator/virtual-nodes")
+	require.Error(t, err)
+}
+
+func TestReleaseRevision(t *testing.T) {
+	revision, err := releaseRevision(`[{"name":"virtual-nodes","namespace":"default","revision":"3","status":"deployed"}]`, "virtual-nodes")
+	require.NoError(t, err)
+	require.Equal(t, "3", revision)
+
+	revision, err = releaseRevision("[]", "virtual-nodes")
+	require.NoError(t, err)
+	require.Empty(t, revision)
+
+	_, err = releaseRevision("Error: not found", "virtual-nodes")
+	require.Error(t, err)
+}
+
+func TestMergeObject(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"}
+	newBinding := func(role string, labels map[string]string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "RoleBinding",
+			"metadata":   map[string]interface{}{"name": "binding", "namespace": "default"},
+			"roleRef":    map[string]interface{}{"apiGroup": "rbac.authorization.k8s.io", "kind": "ClusterRole", "name": role},
+		}}
+		obj.SetLabels(labels)
+		return obj
+	}
+
+	testCases := []struct {
+		name      string
+		immutable bool
+		labels    map[string]string
+	}{
+		{
+			name:   "Case 1: merge keeps fields set by others",
+			labels: map[string]string{"owner": "other", "app": "test"},
+		},
+		{
+			name:      "Case 2: immutable field change re-creates the object",
+			immutable: true,
+			labels:    map[string]string{"app": "test"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{gvr: "RoleBindingList"}, newBinding("view", map[string]string{"owner": "other"}))
+			if tc.immutable {
+				client.PrependReactor("patch", "rolebindings", func(clienttesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewInvalid(schema.GroupKind{Group: gvr.Group, Kind: "RoleBinding"}, "binding", nil)
+				})
+			}
+			rc := client.Resource(gvr).Namespace("default")
+
+			require.NoError(t, mergeObject(context.Background(), rc, newBinding("edit", map[string]string{"app": "test"})))
+
+			obj, err := rc.Get(context.Background(), "binding", metav1.GetOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tc.labels, obj.GetLabels())
+			role, _, _ := unstructured.NestedString(obj.Object, "roleRef", "name")
+			require.Equal(t, "edit", role)
+		})
+	}
+}
@@ -19,6 +19,7 @@ package engine
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"gopkg.in/yaml.v3"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,7 +38,17 @@ type DeleteObjTask struct {
 }
 
 type deleteObjTaskParams struct {
-	RefTaskID string `yaml:"refTaskId"`
+	RefTaskID string     `yaml:"refTaskId"`
+	Target    *ObjTarget `yaml:"target,omitempty"`
+	// PropagationPolicy: deletion propagation policy: "Foreground", "Background" (default) or "Orphan"
+	PropagationPolicy string `yaml:"propagationPolicy,omitempty"`
+	// Wait: wait until the objects, including their finalizers and pods, are deleted
+	Wait bool `yaml:"wait,omitempty"`
+	// Timeout: time limit for Wait; default 5m
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// derived
+	propagation v1.DeletionPropagation
 }
 
 // newDeleteObjTask initializes and returns DeleteObjTask
@@ -72,33 +83,65 @@ func (task *DeleteObjTask) validate(params map[string]interface{}) (err error) {
 		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
 	}
 
-	if len(task.RefTaskID) == 0 {
+	if task.Target != nil {
+		if len(task.RefTaskID) != 0 {
+			return fmt.Errorf("%s: parameters 'refTaskId' and 'target' are mutually exclusive", task.ID())
+		}
+		if err = task.Target.validate(); err != nil {
+			return fmt.Errorf("%s: %v", task.ID(), err)
+		}
+	} else if len(task.RefTaskID) == 0 {
 		return fmt.Errorf("%s: missing parameter 'refTaskId'", task.ID())
 	}
 
+	if task.propagation, err = parsePropagationPolicy(task.PropagationPolicy); err != nil {
+		return fmt.Errorf("%s: %v", task.ID(), err)
+	}
+
+	if task.Timeout < 0 {
+		return fmt.Errorf("%s: 'timeout' must be a positive duration", task.ID())
+	}
+	if task.Wait && task.Timeout == 0 {
+		task.Timeout = DefaultCleanupTimeout
+	}
+
 	return
 }
 
 // Exec implements Runnable interface
 func (task *DeleteObjTask) Exec(ctx context.Context) error {
-	info, err := task.getter.GetObjInfo(task.RefTaskID)
-	if err != nil {
+	var info *ObjInfo
+	var err error
+	if task.Target != nil {
+		if info, err = task.Target.resolve(ctx, task.client, task.getter); err != nil {
+			return fmt.Errorf("%s: %v", task.ID(), err)
+		}
+	} else if info, err = task.getter.GetObjInfo(task.RefTaskID); err != nil {
 		return err
 	}
 
-	prop := v1.DeletePropagationBackground
 	opt := v1.DeleteOptions{
-		PropagationPolicy: &prop,
+		PropagationPolicy: &task.propagation,
 	}
 
-	for _, name := range info.Names {
-		for i := range info.GVR {
-			log.V(4).Infof("Deleting objects %s %v", info.GVR[i].String(), info.Names)
-			err = task.client.Resource(info.GVR[i]).Namespace(info.Namespace).Delete(ctx, name, opt)
+	for i, name := range info.Names {
+		for j := range info.GVR {
+			log.V(4).Infof("Deleting objects %s %v", info.GVR[j].String(), info.Names)
+			err = task.client.Resource(info.GVR[j]).Namespace(info.ObjNamespace(j, i)).Delete(ctx, name, opt)
 			if err != nil {
 				return err
 			}
 		}
 	}
+
+	if !task.Wait {
+		return nil
+	}
+
+	waiter := &deletionWaiter{client: task.client}
+	waiter.addObjects(info, task.propagation)
+	if err = waiter.wait(ctx, task.Timeout); err != nil {
+		return fmt.Errorf("%s: %v", task.ID(), err)
+	}
 	return nil
 }
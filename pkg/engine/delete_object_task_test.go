@@ -17,9 +17,12 @@
 package engine
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/NVIDIA/knavigator/pkg/config"
 )
@@ -66,7 +69,81 @@ func TestNewDeleteObjTask(t *testing.T) {
 					taskID:   taskID,
 				},
 				deleteObjTaskParams: deleteObjTaskParams{
-					RefTaskID: "1",
+					RefTaskID:   "1",
+					propagation: metav1.DeletePropagationBackground,
+				},
+				client: testDynamicClient,
+			},
+		},
+		{
+			name: "Case 5: invalid target",
+			params: map[string]interface{}{
+				"target": map[string]interface{}{
+					"apiVersion": "kubeflow.org/v2beta1",
+					"kind":       "MPIJob",
+					"namespace":  "default",
+				},
+			},
+			simClients: true,
+			err:        "DeleteObj/delete: target must specify labelSelector, fieldSelector and/or names",
+		},
+		{
+			name: "Case 6: valid target",
+			params: map[string]interface{}{
+				"target": map[string]interface{}{
+					"apiVersion":    "kubeflow.org/v2beta1",
+					"kind":          "MPIJob",
+					"namespace":     "default",
+					"labelSelector": "app=test",
+				},
+			},
+			simClients: true,
+			task: &DeleteObjTask{
+				BaseTask: BaseTask{
+					taskType: TaskDeleteObj,
+					taskID:   taskID,
+				},
+				deleteObjTaskParams: deleteObjTaskParams{
+					Target: &ObjTarget{
+						APIVersion:    "kubeflow.org/v2beta1",
+						Kind:          "MPIJob",
+						Namespace:     "default",
+						LabelSelector: "app=test",
+					},
+					propagation: metav1.DeletePropagationBackground,
+				},
+				client: testDynamicClient,
+			},
+		},
+		{
+			name: "Case 7: invalid propagation policy",
+			params: map[string]interface{}{
+				"refTaskId":         1,
+				"propagationPolicy": "background",
+			},
+			simClients: true,
+			err:        "DeleteObj/delete: invalid propagation policy \"background\"; supported: Foreground, Background, Orphan",
+		},
+		{
+			name: "Case 8: wait with default timeout",
+			params: map[string]interface{}{
+				"refTaskId":         1,
+				"propagationPolicy": "Foreground",
+				"wait":              true,
+			},
+			simClients: true,
+			refTaskId:  "1",
+			task: &DeleteObjTask{
+				BaseTask: BaseTask{
+					taskType: TaskDeleteObj,
+					taskID:   taskID,
+				},
+				deleteObjTaskParams: deleteObjTaskParams{
+					RefTaskID:         "1",
+					PropagationPolicy: "Foreground",
+					Wait:              true,
+					Timeout:           DefaultCleanupTimeout,
+					propagation:       metav1.DeletePropagationForeground,
 				},
 				client: testDynamicClient,
 			},
@@ -98,3 +175,34 @@ func TestNewDeleteObjTask(t *testing.T) {
 		})
 	}
 }
+
+func TestDeletionWaiter(t *testing.T) {
+	gvr := []schema.GroupVersionResource{{Group: "batch", Version: "v1", Resource: "jobs"}}
+	info := NewObjInfo([]string{"job1", "job2"}, "default", gvr, 0)
+	info.Labels = map[string]string{LabelRunID: "run1", LabelTaskID: "submit"}
+
+	waiter := &deletionWaiter{}
+	waiter.addObjects(info, metav1.DeletePropagationBackground)
+	require.Equal(t, []objRef{
+		{gvr: gvr[0], namespace: "default", name: "job1"},
+		{gvr: gvr[0], namespace: "default", name: "job2"},
+	}, waiter.refs)
+	require.Equal(t, []string{"knavigator.io/run-id=run1,knavigator.io/task-id=submit"}, waiter.podSelectors)
+	require.Equal(t, "jobs.batch default/job1", waiter.refs[0].String())
+
+	// orphaned pods are not waited for
+	waiter = &deletionWaiter{}
+	waiter.addObjects(info, metav1.DeletePropagationOrphan)
+	require.Len(t, waiter.refs, 2)
+	require.Empty(t, waiter.podSelectors)
+}
+
+func TestSummarize(t *testing.T) {
+	require.Equal(t, "a; b", summarize([]string{"a", "b"}))
+
+	leftovers := []string{}
+	for i := 0; i < maxLeftovers+2; i++ {
+		leftovers = append(leftovers, fmt.Sprintf("pod%d", i))
+	}
+	require.Equal(t, "pod0; pod1; pod2; pod3; pod4; pod5; pod6; pod7; pod8; pod9; and 2 more", summarize(leftovers))
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	log "k8s.io/klog/v2"
+)
+
+const (
+	// deletionPollInterval is the interval between the checks for the deleted objects
+	deletionPollInterval = time.Second
+	// maxLeftovers limits the number of leftover objects listed in the summary
+	maxLeftovers = 10
+)
+
+var podGVR = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+
+// parsePropagationPolicy returns the deletion propagation policy; default is Background
+func parsePropagationPolicy(policy string) (metav1.DeletionPropagation, error) {
+	switch p := metav1.DeletionPropagation(policy); p {
+	case "":
+		return metav1.DeletePropagationBackground, nil
+	case metav1.DeletePropagationForeground, metav1.DeletePropagationBackground, metav1.DeletePropagationOrphan:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid propagation policy %q; supported: %s, %s, %s", policy,
+			metav1.DeletePropagationForeground, metav1.DeletePropagationBackground, metav1.DeletePropagationOrphan)
+	}
+}
+
+// objRef refers to an object to delete
+type objRef struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+// String implements Stringer interface
+func (ref objRef) String() string {
+	return fmt.Sprintf("%s %s", ref.gvr.GroupResource().String(), objectKey(ref.namespace, ref.name))
+}
+
+// objRefs returns references to the objects
+func (info *ObjInfo) objRefs() []objRef {
+	refs := make([]objRef, 0, len(info.Names)*len(info.GVR))
+	for i, name := range info.Names {
+		for j, gvr := range info.GVR {
+			refs = append(refs, objRef{gvr: gvr, namespace: info.ObjNamespace(j, i), name: name})
+		}
+	}
+	return refs
+}
+
+// deletionWaiter waits until the deleted objects, and optionally their pods, are gone
+type deletionWaiter struct {
+	client *dynamic.DynamicClient
+	refs   []objRef
+	// podSelectors: label selectors of the pods spawned by the objects
+	podSelectors []string
+}
+
+// addObjects adds the objects to wait for; with propagation other than Orphan, it adds the pods with their tracking labels
+func (w *deletionWaiter) addObjects(info *ObjInfo, propagation metav1.DeletionPropagation) {
+	w.refs = append(w.refs, info.objRefs()...)
+	if propagation != metav1.DeletePropagationOrphan && len(info.Labels) != 0 {
+		w.podSelectors = append(w.podSelectors, labels.SelectorFromSet(info.Labels).String())
+	}
+}
+
+// wait waits until the objects are deleted, or until the timeout.
+// On timeout, it returns an error with the summary of the objects still present.
+func (w *deletionWaiter) wait(ctx context.Context, timeout time.Duration) error {
+	log.Infof("Waiting for deletion of %d objects with %s timeout", len(w.refs), timeout.String())
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		leftovers := w.leftovers(ctx)
+		if len(leftovers) == 0 {
+			log.Infof("All objects deleted")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d objects still present after %s: %s", len(leftovers), timeout.String(), summarize(leftovers))
+		case <-time.After(deletionPollInterval):
+		}
+	}
+}
+
+// leftovers returns the objects still present, and drops the deleted objects from the waiter
+func (w *deletionWaiter) leftovers(ctx context.Context) []string {
+	leftovers := []string{}
+
+	present := []objRef{}
+	for _, ref := range w.refs {
+		obj, err := w.client.Resource(ref.gvr).Namespace(ref.namespace).Get(ctx, ref.name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		present = append(present, ref)
+		switch {
+		case err != nil:
+			leftovers = append(leftovers, fmt.Sprintf("%s (%v)", ref.String(), err))
+		case obj.GetDeletionTimestamp() != nil && len(obj.GetFinalizers()) != 0:
+			leftovers = append(leftovers, fmt.Sprintf("%s (terminating; finalizers: %s)", ref.String(), strings.Join(obj.GetFinalizers(), ", ")))
+		case obj.GetDeletionTimestamp() != nil:
+			leftovers = append(leftovers, fmt.Sprintf("%s (terminating)", ref.String()))
+		default:
+			leftovers = append(leftovers, ref.String())
+		}
+	}
+	w.refs = present
+
+	for _, selector := range w.podSelectors {
+		list, err := w.client.Resource(podGVR).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			leftovers = append(leftovers, fmt.Sprintf("pods %s (%v)", selector, err))
+			continue
+		}
+		for _, pod := range list.Items {
+			leftovers = append(leftovers, objRef{gvr: podGVR, namespace: pod.GetNamespace(), name: pod.GetName()}.String())
+		}
+	}
+
+	return leftovers
+}
+
+// summarize returns the list of the leftover objects, limited to maxLeftovers
+func summarize(leftovers []string) string {
+	if len(leftovers) <= maxLeftovers {
+		return strings.Join(leftovers, "; ")
+	}
+	return fmt.Sprintf("%s; and %d more", strings.Join(leftovers[:maxLeftovers], "; "), len(leftovers)-maxLeftovers)
+}
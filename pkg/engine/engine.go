@@ -19,17 +19,26 @@ package engine
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	log "k8s.io/klog/v2"
 
 	"github.com/NVIDIA/knavigator/pkg/config"
+	"github.com/NVIDIA/knavigator/pkg/utils"
 )
 
 type Engine interface {
@@ -43,16 +52,29 @@ type Eng struct {
 	k8sClient       *kubernetes.Clientset
 	dynamicClient   *dynamic.DynamicClient
 	discoveryClient *discovery.DiscoveryClient
+	restMapper      meta.RESTMapper
 	objTypeMap      map[string]*RegisterObjParams
 	objInfoMap      map[string]*ObjInfo
+	nodeMap         map[string][]string
+	recoveries      []*Recovery
+	recoveryMap     map[string]*Recovery
 	cleanup         *CleanupInfo
+
+	// rnd is the source of random choices for the tasks that run outside of a workflow run
+	rnd *randSource
+	// objCounter generates object IDs for the tasks that run outside of a workflow run
+	objCounter *utils.Counter
 }
 
 func New(config *rest.Config, cleanupInfo *CleanupInfo, sim ...bool) (*Eng, error) {
 	eng := &Eng{
-		objTypeMap: make(map[string]*RegisterObjParams),
-		objInfoMap: make(map[string]*ObjInfo),
-		cleanup:    cleanupInfo,
+		objTypeMap:  make(map[string]*RegisterObjParams),
+		objInfoMap:  make(map[string]*ObjInfo),
+		nodeMap:     make(map[string][]string),
+		recoveryMap: make(map[string]*Recovery),
+		cleanup:     cleanupInfo,
+		rnd:         newRandSource(time.Now().UnixNano()),
+		objCounter:  utils.NewCounter(0),
 	}
 
 	if len(sim) == 0 { // len(sim) != 0 in unit tests
@@ -72,10 +94,37 @@ func New(config *rest.Config, cleanupInfo *CleanupInfo, sim ...bool) (*Eng, erro
 		eng.discoveryClient = &discovery.DiscoveryClient{}
 	}
 
+	if eng.discoveryClient != nil {
+		// the mapper caches the API resources; GetGVR resets the cache when a kind is not found
+		eng.restMapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(eng.discoveryClient))
+	}
+
 	return eng, nil
 }
 
 func Run(ctx context.Context, eng Engine, workflow *config.Workflow) error {
+	// record the seed in the workflow so that the run can be reproduced
+	if workflow.Seed == nil {
+		seed := time.Now().UnixNano()
+		workflow.Seed = &seed
+	}
+	log.Infof("Workflow %s: random seed %d", workflow.Name, *workflow.Seed)
+
+	// record the run ID in the workflow so that the objects of the run can be found
+	if len(workflow.RunID) == 0 {
+		workflow.RunID = strconv.FormatInt(time.Now().UnixNano(), 36)
+	} else if errs := validation.IsValidLabelValue(workflow.RunID); len(errs) != 0 {
+		return fmt.Errorf("invalid run ID %q: %s", workflow.RunID, strings.Join(errs, "; "))
+	}
+	log.Infof("Workflow %s: run ID %s", workflow.Name, workflow.RunID)
+
+	// each run has its own object ID counter, random number generator and templates,
+	// so that concurrent runs on the same engine do not interfere
+	ctx = withObjCounter(ctx, utils.NewCounter(workflow.ObjectIDStart))
+	ctx = withRunID(ctx, workflow.RunID)
+	ctx = withRandSource(ctx, newRandSource(*workflow.Seed))
+	ctx = withTemplates(ctx, workflow.Templates)
+
 	var errExec error
 	for _, cfg := range workflow.Tasks {
 		if errExec = eng.RunTask(ctx, cfg); errExec != nil {
@@ -92,8 +141,98 @@ func Run(ctx context.Context, eng Engine, workflow *config.Workflow) error {
 	return errReset
 }
 
+// randSource is the source of random choices of a workflow run
+type randSource struct {
+	mutex sync.Mutex
+	rnd   *rand.Rand
+}
+
+// newRandSource returns the source of random choices seeded by the workflow
+func newRandSource(seed int64) *randSource {
+	return &randSource{rnd: rand.New(rand.NewSource(seed))} // #nosec G404 // reproducibility is required
+}
+
+// newRand returns a random number generator derived from the workflow seed.
+// Each task gets its own generator, so that its choices do not depend on the execution of other tasks.
+func (src *randSource) newRand() *rand.Rand {
+	src.mutex.Lock()
+	defer src.mutex.Unlock()
+
+	return rand.New(rand.NewSource(src.rnd.Int63())) // #nosec G404 // reproducibility is required
+}
+
+type randSourceKey struct{}
+
+// withRandSource returns a copy of the context carrying the source of random choices of the workflow run
+func withRandSource(ctx context.Context, src *randSource) context.Context {
+	return context.WithValue(ctx, randSourceKey{}, src)
+}
+
+// randSourceFrom returns the source of random choices of the workflow run, or the default source if not set
+func randSourceFrom(ctx context.Context, def *randSource) *randSource {
+	if src, ok := ctx.Value(randSourceKey{}).(*randSource); ok {
+		return src
+	}
+	return def
+}
+
+type templatesKey struct{}
+
+// withTemplates returns a copy of the context carrying the template files bundled with the workflow
+func withTemplates(ctx context.Context, templates map[string]string) context.Context {
+	return context.WithValue(ctx, templatesKey{}, templates)
+}
+
+// templatesFrom returns the template files bundled with the workflow, or nil if the task runs outside of a workflow run
+func templatesFrom(ctx context.Context) map[string]string {
+	templates, _ := ctx.Value(templatesKey{}).(map[string]string)
+	return templates
+}
+
+type objCounterKey struct{}
+
+// withObjCounter returns a copy of the context carrying the object ID counter of the workflow run
+func withObjCounter(ctx context.Context, counter *utils.Counter) context.Context {
+	return context.WithValue(ctx, objCounterKey{}, counter)
+}
+
+// objCounterFrom returns the object ID counter of the workflow run, or the default counter if not set
+func objCounterFrom(ctx context.Context, def *utils.Counter) *utils.Counter {
+	if counter, ok := ctx.Value(objCounterKey{}).(*utils.Counter); ok {
+		return counter
+	}
+	return def
+}
+
+type runIDKey struct{}
+
+// withRunID returns a copy of the context carrying the ID of the workflow run
+func withRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// runIDFrom returns the ID of the workflow run, or an empty string if the task runs outside of a workflow run
+func runIDFrom(ctx context.Context) string {
+	runID, _ := ctx.Value(runIDKey{}).(string)
+	return runID
+}
+
+type remoteWorkflowKey struct{}
+
+// WithRemoteWorkflow returns a copy of the context marking the workflow run as submitted remotely.
+// The templates of the remote workflows cannot read the environment variables of the server.
+func WithRemoteWorkflow(ctx context.Context) context.Context {
+	return context.WithValue(ctx, remoteWorkflowKey{}, true)
+}
+
+// isRemoteWorkflow returns true if the workflow run was submitted remotely
+func isRemoteWorkflow(ctx context.Context) bool {
+	remote, _ := ctx.Value(remoteWorkflowKey{}).(bool)
+	return remote
+}
+
 func (eng *Eng) RunTask(ctx context.Context, cfg *config.Task) error {
-	runnable, err := eng.GetTask(cfg)
+	runnable, err := eng.getTask(ctx, cfg)
 	if err != nil {
 		return err
 	}
@@ -103,19 +242,43 @@ func (eng *Eng) RunTask(ctx context.Context, cfg *config.Task) error {
 
 // GetTask initializes and validates task
 func (eng *Eng) GetTask(cfg *config.Task) (Runnable, error) {
+	return eng.getTask(context.Background(), cfg)
+}
+
+// getTask initializes and validates task with the random number generator and templates of the workflow run
+func (eng *Eng) getTask(ctx context.Context, cfg *config.Task) (Runnable, error) {
 	eng.mutex.Lock()
 	defer eng.mutex.Unlock()
 
+	rnd := randSourceFrom(ctx, eng.rnd)
+
 	log.Infof("Creating task %s/%s", cfg.Type, cfg.ID)
 	switch cfg.Type {
 	case TaskRegisterObj:
-		return newRegisterObjTask(eng.discoveryClient, eng, cfg)
+		funcs := utils.TemplateFuncs(rnd.newRand())
+		if isRemoteWorkflow(ctx) {
+			utils.DisableEnv(funcs)
+		}
+		return newRegisterObjTask(eng.discoveryClient, eng, funcs, templatesFrom(ctx), cfg)
 
 	case TaskConfigure:
-		return newConfigureTask(eng.k8sClient, cfg)
+		return newConfigureTask(eng.k8sClient, eng.dynamicClient, eng, eng, rnd.newRand(), templatesFrom(ctx), cfg)
+
+	case TaskConfigureScheduler:
+		return newConfigureSchedulerTask(eng.k8sClient, cfg)
+
+	case TaskRestore:
+		task, err := newRestoreTask(eng, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := eng.recoveryMap[task.RefTaskID]; !ok {
+			return nil, fmt.Errorf("%s: unreferenced task ID %s", task.ID(), task.RefTaskID)
+		}
+		return task, nil
 
 	case TaskSubmitObj:
-		task, err := newSubmitObjTask(eng.dynamicClient, eng, cfg)
+		task, err := newSubmitObjTask(eng.dynamicClient, eng, eng.objCounter, cfg)
 		if err != nil {
 			return nil, err
 		}
@@ -129,7 +292,7 @@ func (eng *Eng) GetTask(cfg *config.Task) (Runnable, error) {
 		if err != nil {
 			return nil, err
 		}
-		if _, ok := eng.objInfoMap[task.RefTaskID]; !ok {
+		if _, ok := eng.objInfoMap[task.RefTaskID]; !ok && task.Target == nil {
 			return nil, fmt.Errorf("%s: unreferenced task ID %s", task.ID(), task.RefTaskID)
 		}
 		return task, nil
@@ -139,7 +302,7 @@ func (eng *Eng) GetTask(cfg *config.Task) (Runnable, error) {
 		if err != nil {
 			return nil, err
 		}
-		if _, ok := eng.objInfoMap[task.RefTaskID]; !ok {
+		if _, ok := eng.objInfoMap[task.RefTaskID]; !ok && task.Target == nil {
 			return nil, fmt.Errorf("%s: unreferenced task ID %s", task.ID(), task.RefTaskID)
 		}
 		return task, nil
@@ -149,16 +312,53 @@ func (eng *Eng) GetTask(cfg *config.Task) (Runnable, error) {
 		if err != nil {
 			return nil, err
 		}
-		if _, ok := eng.objInfoMap[task.RefTaskID]; !ok {
+		if _, ok := eng.objInfoMap[task.RefTaskID]; !ok && task.Target == nil {
 			return nil, fmt.Errorf("%s: unreferenced task ID %s", task.ID(), task.RefTaskID)
 		}
 		return task, nil
 
 	case TaskUpdateNodes:
-		return newUpdateNodesTask(eng.k8sClient, cfg)
+		task, err := newUpdateNodesTask(eng.k8sClient, eng, rnd.newRand(), cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err = eng.checkNodesFrom(task.ID(), task.NodesFrom); err != nil {
+			return nil, err
+		}
+		return task, nil
+
+	case TaskInjectNodeFault:
+		task, err := newInjectNodeFaultTask(eng.k8sClient, eng, eng, rnd.newRand(), cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err = eng.checkNodesFrom(task.ID(), task.NodesFrom); err != nil {
+			return nil, err
+		}
+		return task, nil
 
 	case TaskCheckPod:
-		task, err := newCheckPodTask(eng.k8sClient, eng, cfg)
+		task, err := newCheckPodTask(eng.k8sClient, eng.dynamicClient, eng, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := eng.objInfoMap[task.RefTaskID]; !ok && task.Names == nil {
+			return nil, fmt.Errorf("%s: unreferenced task ID %s", task.ID(), task.RefTaskID)
+		}
+		return task, nil
+
+	case TaskCheckEvents:
+		task, err := newCheckEventsTask(eng.k8sClient, eng.dynamicClient, eng, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := eng.objInfoMap[task.RefTaskID]; !ok {
+			return nil, fmt.Errorf("%s: unreferenced task ID %s", task.ID(), task.RefTaskID)
+		}
+		return task, nil
+
+	case TaskInjectFault:
+		task, err := newInjectFaultTask(eng.k8sClient, eng.dynamicClient, eng, rnd.newRand(), cfg)
 		if err != nil {
 			return nil, err
 		}
@@ -174,6 +374,9 @@ func (eng *Eng) GetTask(cfg *config.Task) (Runnable, error) {
 		}
 		return task, nil
 
+	case TaskCheckResource:
+		return newCheckResourceTask(eng.dynamicClient, eng, cfg)
+
 	case TaskSleep:
 		return newSleepTask(cfg)
 
@@ -247,6 +450,67 @@ func (eng *Eng) GetObjInfo(taskID string) (*ObjInfo, error) {
 	return info, nil
 }
 
+// SetSelectedNodes implements NodeSelectionAccessor interface and records the nodes chosen by the task
+func (eng *Eng) SetSelectedNodes(taskID string, names []string) error {
+	eng.mutex.Lock()
+	defer eng.mutex.Unlock()
+
+	if _, ok := eng.nodeMap[taskID]; ok {
+		return fmt.Errorf("SetSelectedNodes: duplicate taskID %s", taskID)
+	}
+
+	eng.nodeMap[taskID] = names
+
+	log.V(4).Infof("Setting selected nodes for task ID %s", taskID)
+
+	return nil
+}
+
+// GetSelectedNodes implements NodeSelectionAccessor interface and returns the nodes chosen by the task
+func (eng *Eng) GetSelectedNodes(taskID string) ([]string, error) {
+	eng.mutex.Lock()
+	defer eng.mutex.Unlock()
+
+	names, ok := eng.nodeMap[taskID]
+	if !ok {
+		return nil, fmt.Errorf("GetSelectedNodes: missing task ID %s", taskID)
+	}
+
+	return names, nil
+}
+
+// checkNodesFrom verifies that the task referred to by nodesFrom has recorded the chosen nodes
+func (eng *Eng) checkNodesFrom(taskID, nodesFrom string) error {
+	if len(nodesFrom) == 0 {
+		return nil
+	}
+	if _, ok := eng.nodeMap[nodesFrom]; !ok {
+		return fmt.Errorf("%s: unreferenced task ID %s", taskID, nodesFrom)
+	}
+	return nil
+}
+
+// GetGVR implements ObjGetter interface and returns GroupVersionResource for given GroupVersionKind
+func (eng *Eng) GetGVR(gvk schema.GroupVersionKind) (schema.GroupVersionResource, bool, error) {
+	mapping, err := eng.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		// the kind may have been installed after the cache was filled, e.g. by a CRD
+		if mapper, ok := eng.restMapper.(meta.ResettableRESTMapper); ok {
+			log.V(4).Infof("Resetting REST mapper to resolve %s", gvk.String())
+			mapper.Reset()
+			mapping, err = eng.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		}
+	}
+	if err != nil {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("GetGVR: failed to find resource for %s: %v", gvk.String(), err)
+	}
+
+	namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+	log.V(4).Infof("Resolved %s to resource %s (namespaced: %v)", gvk.String(), mapping.Resource.Resource, namespaced)
+
+	return mapping.Resource, namespaced, nil
+}
+
 func execRunnable(ctx context.Context, r Runnable) error {
 	id := r.ID()
 	log.Infof("Starting task %s", id)
@@ -259,51 +523,114 @@ func execRunnable(ctx context.Context, r Runnable) error {
 	return nil
 }
 
+// AddRecovery implements Recoverer interface and registers the recovery to run on reset
+func (eng *Eng) AddRecovery(taskID string, r *Recovery) {
+	eng.mutex.Lock()
+	defer eng.mutex.Unlock()
+
+	eng.recoveries = append(eng.recoveries, r)
+	eng.recoveryMap[taskID] = r
+}
+
+// GetRecovery implements Recoverer interface and returns the recovery registered by the task
+func (eng *Eng) GetRecovery(taskID string) (*Recovery, error) {
+	eng.mutex.Lock()
+	defer eng.mutex.Unlock()
+
+	r, ok := eng.recoveryMap[taskID]
+	if !ok {
+		return nil, fmt.Errorf("GetRecovery: missing task ID %s", taskID)
+	}
+	return r, nil
+}
+
 // Reset re-initializes engine and deletes the remaining objects
 func (eng *Eng) Reset(ctx context.Context) error {
 	log.Infof("Reset Engine")
 
+	eng.mutex.Lock()
+	recoveries := eng.recoveries
+	eng.recoveries = nil
+	eng.recoveryMap = make(map[string]*Recovery)
+	eng.mutex.Unlock()
+
+	for _, r := range recoveries {
+		r.Run(ctx)
+	}
+
+	// the task state is cleared after the cleanup, which deletes the objects recorded in it
+	defer eng.resetTaskState()
+
 	if eng.cleanup == nil || !eng.cleanup.Enabled {
 		return nil
 	}
 
 	log.Infof("Cleaning up objects")
-	ctx, cancel := context.WithTimeout(ctx, eng.cleanup.Timeout)
+	deleteCtx, cancel := context.WithTimeout(ctx, eng.cleanup.Timeout)
 	defer cancel()
 
-	stop := make(chan struct{})
+	stop := make(chan struct{}, 1)
 
 	go func() {
-		eng.DeleteAllObjects(ctx)
+		eng.DeleteAllObjects(deleteCtx)
 		stop <- struct{}{}
 	}()
 
 	select {
 	case <-stop:
+	case <-deleteCtx.Done():
+		return deleteCtx.Err()
+	}
+
+	if !eng.cleanup.Wait {
 		return nil
-	case <-ctx.Done():
-		return ctx.Err()
 	}
+
+	waiter := &deletionWaiter{client: eng.dynamicClient}
+	eng.mutex.Lock()
+	for _, objInfo := range eng.objInfoMap {
+		waiter.addObjects(objInfo, eng.cleanup.propagation())
+	}
+	eng.mutex.Unlock()
+
+	// the wait has its own deadline, so that the deletion time does not shorten it
+	return waiter.wait(ctx, eng.cleanup.Timeout)
+}
+
+// resetTaskState clears the state recorded by the tasks, so that the next workflow can reuse the task IDs
+func (eng *Eng) resetTaskState() {
+	eng.mutex.Lock()
+	defer eng.mutex.Unlock()
+
+	eng.objTypeMap = make(map[string]*RegisterObjParams)
+	eng.objInfoMap = make(map[string]*ObjInfo)
+	eng.nodeMap = make(map[string][]string)
 }
 
 // DeleteAllObjects deletes all objects
 func (eng *Eng) DeleteAllObjects(ctx context.Context) {
 	deletePolicy := metav1.DeletePropagationBackground
+	if eng.cleanup != nil {
+		deletePolicy = eng.cleanup.propagation()
+	}
 	deletions := metav1.DeleteOptions{
 		PropagationPolicy: &deletePolicy,
 	}
 
+	var deleted, failed int
 	for _, objInfo := range eng.objInfoMap {
-		ns := objInfo.Namespace
-		for _, name := range objInfo.Names {
-			for i := range objInfo.GVR {
-				err := eng.dynamicClient.Resource(objInfo.GVR[i]).Namespace(ns).Delete(ctx, name, deletions)
+		for i, name := range objInfo.Names {
+			for j := range objInfo.GVR {
+				err := eng.dynamicClient.Resource(objInfo.GVR[j]).Namespace(objInfo.ObjNamespace(j, i)).Delete(ctx, name, deletions)
 				if err != nil {
 					log.Infof("Warning: cannot delete object %s: %v", name, err)
+					failed++
+				} else {
+					deleted++
 				}
 			}
 		}
 	}
 
-	log.Infof("Deleted all objects")
+	log.Infof("Deleted %d objects with %s propagation; failed to delete %d objects", deleted, deletePolicy, failed)
 }
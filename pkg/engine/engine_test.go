@@ -22,11 +22,15 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/NVIDIA/knavigator/pkg/config"
+	"github.com/NVIDIA/knavigator/pkg/utils"
 )
 
 var (
@@ -38,12 +42,23 @@ var (
 	testDiscoveryClient = &discovery.DiscoveryClient{}
 )
 
+// newTestNameSelector returns finalized NameSelector for given YAML input
+func newTestNameSelector(t *testing.T, input string) *utils.NameSelector {
+	var ns utils.NameSelector
+	require.NoError(t, yaml.Unmarshal([]byte(input), &ns))
+	ns.Init()
+	require.NoError(t, ns.Finalize())
+	return &ns
+}
+
 type testEngine struct {
 	execErr  error
 	resetErr error
+	rnd      *randSource
 }
 
-func (eng *testEngine) RunTask(context.Context, *config.Task) error {
+func (eng *testEngine) RunTask(ctx context.Context, _ *config.Task) error {
+	eng.rnd = randSourceFrom(ctx, nil)
 	return eng.execErr
 }
 
@@ -93,6 +108,142 @@ func TestRunEngine(t *testing.T) {
 	}
 }
 
+func TestRunSeed(t *testing.T) {
+	ctx := context.Background()
+	seed := int64(42)
+
+	// the seed set by the workflow is passed to the tasks
+	eng := &testEngine{}
+	workflow := &config.Workflow{Name: "test", Seed: &seed, Tasks: []*config.Task{{ID: "task"}}}
+	require.NoError(t, Run(ctx, eng, workflow))
+	require.NotNil(t, eng.rnd)
+	require.Equal(t, newRandSource(seed).newRand().Int63(), eng.rnd.newRand().Int63())
+
+	// the generated seed is recorded in the workflow
+	eng = &testEngine{}
+	workflow = &config.Workflow{Name: "test", Tasks: []*config.Task{{ID: "task"}}}
+	require.NoError(t, Run(ctx, eng, workflow))
+	require.NotNil(t, workflow.Seed)
+	require.Equal(t, newRandSource(*workflow.Seed).newRand().Int63(), eng.rnd.newRand().Int63())
+}
+
+func TestRunID(t *testing.T) {
+	ctx := context.Background()
+
+	// the generated run ID is recorded in the workflow
+	workflow := &config.Workflow{Name: "test", Tasks: []*config.Task{{ID: "task"}}}
+	require.NoError(t, Run(ctx, &testEngine{}, workflow))
+	require.NotEmpty(t, workflow.RunID)
+
+	// the run ID must be a valid label value
+	workflow = &config.Workflow{Name: "test", RunID: "run/1", Tasks: []*config.Task{{ID: "task"}}}
+	require.ErrorContains(t, Run(ctx, &testEngine{}, workflow), `invalid run ID "run/1"`)
+
+	require.Equal(t, "", runIDFrom(ctx))
+	require.Equal(t, "run1", runIDFrom(withRunID(ctx, "run1")))
+}
+
+func TestRandSource(t *testing.T) {
+	values := func() []int64 {
+		src := newRandSource(42)
+		return []int64{src.newRand().Int63(), src.newRand().Int63()}
+	}
+
+	first := values()
+	require.NotEqual(t, first[0], first[1])
+	require.Equal(t, first, values())
+
+	def := newRandSource(1)
+	require.Equal(t, def, randSourceFrom(context.Background(), def))
+	src := newRandSource(42)
+	require.Equal(t, src, randSourceFrom(withRandSource(context.Background(), src), def))
+}
+
+func TestTemplates(t *testing.T) {
+	templates := map[string]string{"job.yaml": "kind: Job"}
+	require.Nil(t, templatesFrom(context.Background()))
+	require.Equal(t, templates, templatesFrom(withTemplates(context.Background(), templates)))
+}
+
+func TestObjCounter(t *testing.T) {
+	def := utils.NewCounter(0)
+	require.Equal(t, def, objCounterFrom(context.Background(), def))
+
+	counter := utils.NewCounter(100)
+	ctx := withObjCounter(context.Background(), counter)
+	require.Equal(t, counter, objCounterFrom(ctx, def))
+	require.Equal(t, int64(101), objCounterFrom(ctx, def).Next())
+}
+
+func TestGetGVR(t *testing.T) {
+	eng, err := New(nil, nil, true)
+	require.NoError(t, err)
+
+	jobGVK := schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}
+	nodeGVK := schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(jobGVK, meta.RESTScopeNamespace)
+	mapper.Add(nodeGVK, meta.RESTScopeRoot)
+	eng.restMapper = mapper
+
+	gvr, namespaced, err := eng.GetGVR(jobGVK)
+	require.NoError(t, err)
+	require.Equal(t, schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}, gvr)
+	require.True(t, namespaced)
+
+	gvr, namespaced, err = eng.GetGVR(nodeGVK)
+	require.NoError(t, err)
+	require.Equal(t, schema.GroupVersionResource{Version: "v1", Resource: "nodes"}, gvr)
+	require.False(t, namespaced)
+
+	_, _, err = eng.GetGVR(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Unknown"})
+	require.Error(t, err)
+}
+
+// testResettableMapper adds the pending kinds on reset, as if they had been installed
+type testResettableMapper struct {
+	*meta.DefaultRESTMapper
+	pending []schema.GroupVersionKind
+	resets  int
+}
+
+func (m *testResettableMapper) Reset() {
+	m.resets++
+	for _, gvk := range m.pending {
+		m.Add(gvk, meta.RESTScopeNamespace)
+	}
+	m.pending = nil
+}
+
+func TestGetGVRReset(t *testing.T) {
+	eng, err := New(nil, nil, true)
+	require.NoError(t, err)
+
+	crdGVK := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "MyObject"}
+	mapper := &testResettableMapper{
+		DefaultRESTMapper: meta.NewDefaultRESTMapper(nil),
+		pending:           []schema.GroupVersionKind{crdGVK},
+	}
+	eng.restMapper = mapper
+
+	gvr, namespaced, err := eng.GetGVR(crdGVK)
+	require.NoError(t, err)
+	require.Equal(t, schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "myobjects"}, gvr)
+	require.True(t, namespaced)
+	require.Equal(t, 1, mapper.resets)
+
+	// the known kind does not reset the cache
+	_, _, err = eng.GetGVR(crdGVK)
+	require.NoError(t, err)
+	require.Equal(t, 1, mapper.resets)
+
+	// the unknown kind is retried once
+	_, _, err = eng.GetGVR(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Unknown"})
+	require.Error(t, err)
+	require.Equal(t, 2, mapper.resets)
+}
+
 type testRunnable struct {
 	err error
 }
@@ -135,3 +286,39 @@ func TestExecRunnable(t *testing.T) {
 		})
 	}
 }
+
+func TestResetRecoveries(t *testing.T) {
+	eng, err := New(nil, nil, true)
+	require.NoError(t, err)
+
+	var calls int
+	r := NewRecovery("test", func(context.Context) error {
+		calls++
+		return nil
+	})
+	eng.AddRecovery("test", r)
+
+	require.NoError(t, eng.Reset(context.Background()))
+	require.Equal(t, 1, calls)
+	require.Empty(t, eng.recoveries)
+
+	// the recovery runs only once
+	r.Run(context.Background())
+	require.Equal(t, 1, calls)
+}
+
+func TestResetTaskState(t *testing.T) {
+	eng, err := New(nil, nil, true)
+	require.NoError(t, err)
+
+	require.NoError(t, eng.SetObjType("register", &RegisterObjParams{}))
+	require.NoError(t, eng.SetObjInfo("job", NewObjInfo([]string{"job1"}, "default", nil, 1)))
+	require.NoError(t, eng.SetSelectedNodes("fault", []string{"node1"}))
+
+	require.NoError(t, eng.Reset(context.Background()))
+
+	// the next workflow reuses the task IDs
+	require.NoError(t, eng.SetObjType("register", &RegisterObjParams{}))
+	require.NoError(t, eng.SetObjInfo("job", NewObjInfo([]string{"job1"}, "default", nil, 1)))
+	require.NoError(t, eng.SetSelectedNodes("fault", []string{"node2"}))
+}
@@ -0,0 +1,350 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	log "k8s.io/klog/v2"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+)
+
+const (
+	FaultFail    = "fail"
+	FaultOOMKill = "oomKill"
+	// FaultEvict evicts the pods through the Eviction API, subject to the pod disruption budgets
+	FaultEvict = "evict"
+	// FaultNodePressure fails the pods as evicted by the kubelet under node memory pressure
+	FaultNodePressure = "nodePressure"
+	FaultDelete       = "delete"
+	FaultKwokStage    = "kwokStage"
+)
+
+// InjectFaultTask represents a task that injects faults into the pods spawned by the objects
+// submitted by a SubmitObj task. The pods are expected to be managed by KWOK.
+type InjectFaultTask struct {
+	BaseTask
+	injectFaultTaskParams
+
+	client    *kubernetes.Clientset
+	dynClient *dynamic.DynamicClient
+	accessor  ObjInfoAccessor
+	rnd       *rand.Rand
+}
+
+type injectFaultTaskParams struct {
+	RefTaskID string `yaml:"refTaskId"`
+	// Fault is one of "fail", "oomKill", "evict", "nodePressure", "delete", "kwokStage"
+	Fault string `yaml:"fault"`
+	// Fraction of the active pods to inject the fault into, in (0, 1]; default is 1.
+	// Mutually exclusive with Count.
+	Fraction float64 `yaml:"fraction,omitempty"`
+	// Count is the number of the active pods to inject the fault into
+	Count int `yaml:"count,omitempty"`
+	// Delay before injecting the fault
+	Delay time.Duration `yaml:"delay,omitempty"`
+	// Reason, Message and ExitCode override the defaults for the container termination state
+	Reason   string `yaml:"reason,omitempty"`
+	Message  string `yaml:"message,omitempty"`
+	ExitCode *int32 `yaml:"exitCode,omitempty"`
+	// Labels and Annotations are applied to the pods for the "kwokStage" fault
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// newInjectFaultTask initializes and returns InjectFaultTask
+func newInjectFaultTask(client *kubernetes.Clientset, dynClient *dynamic.DynamicClient, accessor ObjInfoAccessor, rnd *rand.Rand, cfg *config.Task) (*InjectFaultTask, error) {
+	if client == nil {
+		return nil, fmt.Errorf("%s/%s: Kubernetes client is not set", cfg.Type, cfg.ID)
+	}
+
+	task := &InjectFaultTask{
+		BaseTask: BaseTask{
+			taskType: cfg.Type,
+			taskID:   cfg.ID,
+		},
+		client:    client,
+		dynClient: dynClient,
+		accessor:  accessor,
+		rnd:       rnd,
+	}
+
+	if err := task.validate(cfg.Params); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// validate initializes and validates parameters for InjectFaultTask
+func (task *InjectFaultTask) validate(params map[string]interface{}) error {
+	data, err := yaml.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
+	}
+	if err = yaml.Unmarshal(data, &task.injectFaultTaskParams); err != nil {
+		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
+	}
+
+	if len(task.RefTaskID) == 0 {
+		return fmt.Errorf("%s: missing parameter 'refTaskId'", task.ID())
+	}
+
+	switch task.Fault {
+	case FaultFail, FaultOOMKill, FaultEvict, FaultNodePressure, FaultDelete:
+		if len(task.Labels) != 0 || len(task.Annotations) != 0 {
+			return fmt.Errorf("%s: parameters 'labels' and 'annotations' are only supported by fault %q", task.ID(), FaultKwokStage)
+		}
+	case FaultKwokStage:
+		if len(task.Labels) == 0 && len(task.Annotations) == 0 {
+			return fmt.Errorf("%s: fault %q requires 'labels' and/or 'annotations'", task.ID(), FaultKwokStage)
+		}
+	default:
+		return fmt.Errorf("%s: invalid fault %q; supported: %s, %s, %s, %s, %s, %s", task.ID(), task.Fault,
+			FaultFail, FaultOOMKill, FaultEvict, FaultNodePressure, FaultDelete, FaultKwokStage)
+	}
+
+	if task.Fraction != 0 && task.Count != 0 {
+		return fmt.Errorf("%s: parameters 'fraction' and 'count' are mutually exclusive", task.ID())
+	}
+	if task.Fraction < 0 || task.Fraction > 1 {
+		return fmt.Errorf("%s: parameter 'fraction' must be in the range (0, 1]", task.ID())
+	}
+	if task.Count < 0 {
+		return fmt.Errorf("%s: parameter 'count' must be positive", task.ID())
+	}
+	if task.Fraction == 0 && task.Count == 0 {
+		task.Fraction = 1
+	}
+
+	if task.Delay < 0 {
+		return fmt.Errorf("%s: parameter 'delay' must not be negative", task.ID())
+	}
+
+	return nil
+}
+
+// Exec implements Runnable interface
+func (task *InjectFaultTask) Exec(ctx context.Context) error {
+	info, err := task.accessor.GetObjInfo(task.RefTaskID)
+	if err != nil {
+		return err
+	}
+
+	podSet, err := newRefPodSet(ctx, task.dynClient, task.accessor, info)
+	if err != nil {
+		return fmt.Errorf("%s: %v", task.ID(), err)
+	}
+
+	if task.Delay > 0 {
+		log.Infof("Wait %s before injecting fault %s", task.Delay.String(), task.Fault)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(task.Delay):
+		}
+	}
+
+	list, err := task.client.CoreV1().Pods(podSet.namespace).List(ctx, metav1.ListOptions{LabelSelector: podSet.selector})
+	if err != nil {
+		return fmt.Errorf("%s: failed to list pods: %v", task.ID(), err)
+	}
+
+	pods := []*v1.Pod{}
+	for i := range list.Items {
+		if pod := &list.Items[i]; podSet.isMatch(pod) && isPodActive(pod) {
+			pods = append(pods, pod)
+		}
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("%s: no active pods to inject fault", task.ID())
+	}
+
+	pods = task.selectPods(pods, task.rnd)
+
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		if err = task.inject(ctx, pod); err != nil {
+			return fmt.Errorf("%s: failed to inject fault %s into pod %s: %v", task.ID(), task.Fault, pod.Name, err)
+		}
+		names = append(names, pod.Name)
+	}
+
+	log.Infof("Injected fault %s into %d pods: %v", task.Fault, len(names), names)
+
+	return nil
+}
+
+// selectPods returns a random subset of the pods according to the requested count or fraction
+func (task *InjectFaultTask) selectPods(pods []*v1.Pod, rnd *rand.Rand) []*v1.Pod {
+	n := task.Count
+	if n == 0 {
+		n = int(math.Ceil(task.Fraction * float64(len(pods))))
+	}
+	if n >= len(pods) {
+		return pods
+	}
+
+	rnd.Shuffle(len(pods), func(i, j int) { pods[i], pods[j] = pods[j], pods[i] })
+
+	return pods[:n]
+}
+
+// inject injects the fault into the pod
+func (task *InjectFaultTask) inject(ctx context.Context, pod *v1.Pod) error {
+	podClient := task.client.CoreV1().Pods(pod.Namespace)
+
+	switch task.Fault {
+	case FaultDelete:
+		return podClient.Delete(ctx, pod.Name, metav1.DeleteOptions{})
+
+	case FaultEvict:
+		err := task.client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		})
+		if errors.IsTooManyRequests(err) {
+			return fmt.Errorf("eviction is blocked by disruption budget: %v", err)
+		}
+		return err
+
+	case FaultKwokStage:
+		patch, err := task.metadataPatch()
+		if err != nil {
+			return err
+		}
+		_, err = podClient.Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+
+	default:
+		patch, err := json.Marshal(map[string]interface{}{"status": task.faultStatus(pod, metav1.Now())})
+		if err != nil {
+			return err
+		}
+		_, err = podClient.Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{}, "status")
+		return err
+	}
+}
+
+// metadataPatch returns the merge patch of the pod labels and annotations for the "kwokStage" fault.
+// The unset maps are omitted, since null values delete the fields under JSON merge patch.
+func (task *InjectFaultTask) metadataPatch() ([]byte, error) {
+	metadata := map[string]interface{}{}
+	if len(task.Labels) != 0 {
+		metadata["labels"] = task.Labels
+	}
+	if len(task.Annotations) != 0 {
+		metadata["annotations"] = task.Annotations
+	}
+	return json.Marshal(map[string]interface{}{"metadata": metadata})
+}
+
+// faultStatus returns the status of the failed pod
+func (task *InjectFaultTask) faultStatus(pod *v1.Pod, now metav1.Time) *v1.PodStatus {
+	reason, exitCode := "Error", int32(1)
+	var podReason, podMessage string
+
+	switch task.Fault {
+	case FaultOOMKill:
+		reason, exitCode = "OOMKilled", 137
+	case FaultNodePressure:
+		reason, exitCode = "ContainerStatusUnknown", 137
+		podReason, podMessage = "Evicted", "The node was low on resource: memory."
+	}
+
+	if len(task.Reason) != 0 {
+		reason = task.Reason
+	}
+	if task.ExitCode != nil {
+		exitCode = *task.ExitCode
+	}
+	if len(task.Message) != 0 {
+		podMessage = task.Message
+	}
+
+	status := pod.Status.DeepCopy()
+	status.Phase = v1.PodFailed
+	status.Reason = podReason
+	status.Message = podMessage
+
+	started := make(map[string]metav1.Time)
+	for _, cs := range status.ContainerStatuses {
+		if cs.State.Running != nil {
+			started[cs.Name] = cs.State.Running.StartedAt
+		}
+	}
+
+	status.ContainerStatuses = make([]v1.ContainerStatus, 0, len(pod.Spec.Containers))
+	for _, c := range pod.Spec.Containers {
+		status.ContainerStatuses = append(status.ContainerStatuses, v1.ContainerStatus{
+			Name:  c.Name,
+			Image: c.Image,
+			State: v1.ContainerState{
+				Terminated: &v1.ContainerStateTerminated{
+					ExitCode:   exitCode,
+					Reason:     reason,
+					Message:    task.Message,
+					StartedAt:  started[c.Name],
+					FinishedAt: now,
+				},
+			},
+		})
+	}
+
+	conditions := []v1.PodCondition{}
+	for _, cond := range status.Conditions {
+		switch cond.Type {
+		case v1.PodReady, v1.ContainersReady:
+			cond.Status = v1.ConditionFalse
+			cond.Reason = "PodFailed"
+			cond.LastTransitionTime = now
+		case v1.DisruptionTarget:
+			continue
+		}
+		conditions = append(conditions, cond)
+	}
+	if task.Fault == FaultNodePressure {
+		conditions = append(conditions, v1.PodCondition{
+			Type:               v1.DisruptionTarget,
+			Status:             v1.ConditionTrue,
+			Reason:             "TerminationByKubelet",
+			Message:            podMessage,
+			LastTransitionTime: now,
+		})
+	}
+	status.Conditions = conditions
+
+	return status
+}
+
+// isPodActive returns true if the pod is not terminated or being deleted
+func isPodActive(pod *v1.Pod) bool {
+	return pod.DeletionTimestamp == nil && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed
+}
@@ -0,0 +1,328 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+)
+
+func TestInjectFaultParams(t *testing.T) {
+	taskID := "fault"
+	testCases := []struct {
+		name       string
+		simClients bool
+		params     map[string]interface{}
+		refTaskId  string
+		err        string
+		task       *InjectFaultTask
+	}{
+		{
+			name:   "Case 1: no k8s client",
+			params: nil,
+			err:    "InjectFault/fault: Kubernetes client is not set",
+		},
+		{
+			name:       "Case 2: missing task reference ID",
+			simClients: true,
+			params:     map[string]interface{}{"fault": "fail"},
+			err:        "InjectFault/fault: missing parameter 'refTaskId'",
+		},
+		{
+			name:       "Case 3: invalid fault",
+			simClients: true,
+			params:     map[string]interface{}{"refTaskId": "job", "fault": "crash"},
+			err:        "InjectFault/fault: invalid fault \"crash\"; supported: fail, oomKill, evict, nodePressure, delete, kwokStage",
+		},
+		{
+			name:       "Case 4a: kwok stage without labels",
+			simClients: true,
+			params:     map[string]interface{}{"refTaskId": "job", "fault": "kwokStage"},
+			err:        "InjectFault/fault: fault \"kwokStage\" requires 'labels' and/or 'annotations'",
+		},
+		{
+			name:       "Case 4b: labels without kwok stage",
+			simClients: true,
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"fault":     "fail",
+				"labels":    map[string]string{"pod-complete.stage.kwok.x-k8s.io": "true"},
+			},
+			err: "InjectFault/fault: parameters 'labels' and 'annotations' are only supported by fault \"kwokStage\"",
+		},
+		{
+			name:       "Case 5a: fraction and count",
+			simClients: true,
+			params:     map[string]interface{}{"refTaskId": "job", "fault": "fail", "fraction": 0.5, "count": 2},
+			err:        "InjectFault/fault: parameters 'fraction' and 'count' are mutually exclusive",
+		},
+		{
+			name:       "Case 5b: invalid fraction",
+			simClients: true,
+			params:     map[string]interface{}{"refTaskId": "job", "fault": "fail", "fraction": 1.5},
+			err:        "InjectFault/fault: parameter 'fraction' must be in the range (0, 1]",
+		},
+		{
+			name:       "Case 6: unreferenced task",
+			simClients: true,
+			params:     map[string]interface{}{"refTaskId": "job", "fault": "delete"},
+			err:        "InjectFault/fault: unreferenced task ID job",
+		},
+		{
+			name:       "Case 7: valid parameters",
+			simClients: true,
+			params: map[string]interface{}{
+				"refTaskId": "job",
+				"fault":     "kwokStage",
+				"count":     2,
+				"delay":     "10s",
+				"labels":    map[string]string{"pod-complete.stage.kwok.x-k8s.io": "true"},
+			},
+			refTaskId: "job",
+			task: &InjectFaultTask{
+				BaseTask: BaseTask{
+					taskType: TaskInjectFault,
+					taskID:   taskID,
+				},
+				injectFaultTaskParams: injectFaultTaskParams{
+					RefTaskID: "job",
+					Fault:     FaultKwokStage,
+					Count:     2,
+					Delay:     10 * time.Second,
+					Labels:    map[string]string{"pod-complete.stage.kwok.x-k8s.io": "true"},
+				},
+				client:    testK8sClient,
+				dynClient: testDynamicClient,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			eng, err := New(nil, nil, tc.simClients)
+			require.NoError(t, err)
+			if len(tc.refTaskId) != 0 {
+				eng.objInfoMap[tc.refTaskId] = nil
+			}
+			task, err := eng.GetTask(&config.Task{
+				ID:     taskID,
+				Type:   TaskInjectFault,
+				Params: tc.params,
+			})
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+				require.Nil(t, tc.task)
+			} else {
+				tc.task.accessor = eng
+				require.NoError(t, err)
+				require.NotNil(t, tc.task)
+				// the random number generator is derived from the workflow seed
+				require.NotNil(t, task.(*InjectFaultTask).rnd)
+				tc.task.rnd = task.(*InjectFaultTask).rnd
+				require.Equal(t, tc.task, task)
+			}
+		})
+	}
+}
+
+func TestInjectFaultSelectPods(t *testing.T) {
+	newPods := func() []*v1.Pod {
+		pods := make([]*v1.Pod, 10)
+		for i := range pods {
+			pods[i] = &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i)}}
+		}
+		return pods
+	}
+
+	testCases := []struct {
+		name     string
+		params   injectFaultTaskParams
+		expected int
+	}{
+		{
+			name:     "Case 1: all pods",
+			params:   injectFaultTaskParams{Fraction: 1},
+			expected: 10,
+		},
+		{
+			name:     "Case 2: fraction",
+			params:   injectFaultTaskParams{Fraction: 0.25},
+			expected: 3,
+		},
+		{
+			name:     "Case 3: count",
+			params:   injectFaultTaskParams{Count: 4},
+			expected: 4,
+		},
+		{
+			name:     "Case 4: count exceeds number of pods",
+			params:   injectFaultTaskParams{Count: 20},
+			expected: 10,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &InjectFaultTask{injectFaultTaskParams: tc.params}
+			pods := task.selectPods(newPods(), rand.New(rand.NewSource(1)))
+			require.Len(t, pods, tc.expected)
+
+			names := make(map[string]bool)
+			for _, pod := range pods {
+				names[pod.Name] = true
+			}
+			require.Len(t, names, tc.expected)
+		})
+	}
+}
+
+func TestInjectFaultMetadataPatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		params   injectFaultTaskParams
+		expected string
+	}{
+		{
+			name:     "Case 1: annotations only",
+			params:   injectFaultTaskParams{Annotations: map[string]string{"a": "b"}},
+			expected: `{"metadata":{"annotations":{"a":"b"}}}`,
+		},
+		{
+			name:     "Case 2: labels only",
+			params:   injectFaultTaskParams{Labels: map[string]string{"c": "d"}},
+			expected: `{"metadata":{"labels":{"c":"d"}}}`,
+		},
+		{
+			name: "Case 3: labels and annotations",
+			params: injectFaultTaskParams{
+				Labels:      map[string]string{"c": "d"},
+				Annotations: map[string]string{"a": "b"},
+			},
+			expected: `{"metadata":{"annotations":{"a":"b"},"labels":{"c":"d"}}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &InjectFaultTask{injectFaultTaskParams: tc.params}
+			patch, err := task.metadataPatch()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, string(patch))
+		})
+	}
+}
+
+func TestInjectFaultStatus(t *testing.T) {
+	now := metav1.Now()
+	started := metav1.NewTime(now.Add(-time.Minute))
+	exitCode := int32(42)
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod"},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "main", Image: "ubuntu"}},
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+			Conditions: []v1.PodCondition{
+				{Type: v1.PodScheduled, Status: v1.ConditionTrue},
+				{Type: v1.PodReady, Status: v1.ConditionTrue},
+			},
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: "main", Image: "ubuntu", Ready: true, State: v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: started}}},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name       string
+		params     injectFaultTaskParams
+		reason     string
+		exitCode   int32
+		podReason  string
+		conditions []v1.PodCondition
+	}{
+		{
+			name:     "Case 1: fail",
+			params:   injectFaultTaskParams{Fault: FaultFail},
+			reason:   "Error",
+			exitCode: 1,
+			conditions: []v1.PodCondition{
+				{Type: v1.PodScheduled, Status: v1.ConditionTrue},
+				{Type: v1.PodReady, Status: v1.ConditionFalse, Reason: "PodFailed", LastTransitionTime: now},
+			},
+		},
+		{
+			name:     "Case 2: OOM kill with exit code",
+			params:   injectFaultTaskParams{Fault: FaultOOMKill, ExitCode: &exitCode},
+			reason:   "OOMKilled",
+			exitCode: 42,
+			conditions: []v1.PodCondition{
+				{Type: v1.PodScheduled, Status: v1.ConditionTrue},
+				{Type: v1.PodReady, Status: v1.ConditionFalse, Reason: "PodFailed", LastTransitionTime: now},
+			},
+		},
+		{
+			name:      "Case 3: node pressure eviction",
+			params:    injectFaultTaskParams{Fault: FaultNodePressure},
+			reason:    "ContainerStatusUnknown",
+			exitCode:  137,
+			podReason: "Evicted",
+			conditions: []v1.PodCondition{
+				{Type: v1.PodScheduled, Status: v1.ConditionTrue},
+				{Type: v1.PodReady, Status: v1.ConditionFalse, Reason: "PodFailed", LastTransitionTime: now},
+				{Type: v1.DisruptionTarget, Status: v1.ConditionTrue, Reason: "TerminationByKubelet",
+					Message: "The node was low on resource: memory.", LastTransitionTime: now},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &InjectFaultTask{injectFaultTaskParams: tc.params}
+			status := task.faultStatus(pod, now)
+
+			require.Equal(t, v1.PodFailed, status.Phase)
+			require.Equal(t, tc.podReason, status.Reason)
+			require.Equal(t, tc.conditions, status.Conditions)
+			require.Equal(t, []v1.ContainerStatus{
+				{
+					Name:  "main",
+					Image: "ubuntu",
+					State: v1.ContainerState{
+						Terminated: &v1.ContainerStateTerminated{
+							ExitCode:   tc.exitCode,
+							Reason:     tc.reason,
+							StartedAt:  started,
+							FinishedAt: now,
+						},
+					},
+				},
+			}, status.ContainerStatuses)
+			// the original pod is not modified
+			require.Equal(t, v1.PodRunning, pod.Status.Phase)
+		})
+	}
+}
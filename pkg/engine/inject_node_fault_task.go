@@ -0,0 +1,416 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	log "k8s.io/klog/v2"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+)
+
+const (
+	NodeFaultNotReady = "notReady"
+	NodeFaultCordon   = "cordon"
+	NodeFaultDrain    = "drain"
+	NodeFaultTaint    = "taint"
+	NodeFaultDelete   = "delete"
+
+	// DefaultFaultTaintKey is the key of the taint added by the "taint" fault if no taints are specified
+	DefaultFaultTaintKey = "knavigator.io/fault"
+)
+
+// InjectNodeFaultTask represents a task that simulates node failure or maintenance
+// for the selected nodes, and optionally recovers the nodes after a given duration.
+type InjectNodeFaultTask struct {
+	BaseTask
+	injectNodeFaultTaskParams
+
+	client    *kubernetes.Clientset
+	recoverer Recoverer
+	accessor  NodeSelectionAccessor
+	rnd       *rand.Rand
+}
+
+type injectNodeFaultTaskParams struct {
+	nodeSelection `yaml:",inline"`
+
+	// Fault is one of "notReady", "cordon", "drain", "taint", "delete"
+	Fault string `yaml:"fault"`
+	// Taints are added by the "taint" fault; default is "knavigator.io/fault:NoExecute"
+	Taints []v1.Taint `yaml:"taints,omitempty"`
+	// RecoverAfter: if set, the nodes are recovered after the given duration. In any case, the nodes are
+	// recovered when the workflow ends, or by a Restore task, whichever comes first
+	RecoverAfter time.Duration `yaml:"recoverAfter,omitempty"`
+}
+
+// newInjectNodeFaultTask initializes and returns InjectNodeFaultTask
+func newInjectNodeFaultTask(client *kubernetes.Clientset, recoverer Recoverer, accessor NodeSelectionAccessor, rnd *rand.Rand, cfg *config.Task) (*InjectNodeFaultTask, error) {
+	if client == nil {
+		return nil, fmt.Errorf("%s/%s: Kubernetes client is not set", cfg.Type, cfg.ID)
+	}
+
+	task := &InjectNodeFaultTask{
+		BaseTask: BaseTask{
+			taskType: cfg.Type,
+			taskID:   cfg.ID,
+		},
+		client:    client,
+		recoverer: recoverer,
+		accessor:  accessor,
+		rnd:       rnd,
+	}
+
+	if err := task.validate(cfg.Params); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// validate initializes and validates parameters for InjectNodeFaultTask
+func (task *InjectNodeFaultTask) validate(params map[string]interface{}) error {
+	data, err := yaml.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
+	}
+	if err = yaml.Unmarshal(data, &task.injectNodeFaultTaskParams); err != nil {
+		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
+	}
+
+	if task.isEmpty() {
+		return fmt.Errorf("%s: missing node selectors", task.ID())
+	}
+	if err = task.nodeSelection.validate(); err != nil {
+		return fmt.Errorf("%s: invalid node names: %v", task.ID(), err)
+	}
+
+	switch task.Fault {
+	case NodeFaultNotReady, NodeFaultCordon, NodeFaultDrain, NodeFaultDelete:
+		if len(task.Taints) != 0 {
+			return fmt.Errorf("%s: parameter 'taints' is only supported by fault %q", task.ID(), NodeFaultTaint)
+		}
+	case NodeFaultTaint:
+		if len(task.Taints) == 0 {
+			task.Taints = []v1.Taint{{Key: DefaultFaultTaintKey, Effect: v1.TaintEffectNoExecute}}
+		}
+		for _, taint := range task.Taints {
+			switch taint.Effect {
+			case v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+				// nop
+			default:
+				return fmt.Errorf("%s: invalid taint effect %q", task.ID(), taint.Effect)
+			}
+			if len(taint.Key) == 0 {
+				return fmt.Errorf("%s: missing taint key", task.ID())
+			}
+		}
+	default:
+		return fmt.Errorf("%s: invalid fault %q; supported: %s, %s, %s, %s, %s", task.ID(), task.Fault,
+			NodeFaultNotReady, NodeFaultCordon, NodeFaultDrain, NodeFaultTaint, NodeFaultDelete)
+	}
+
+	if err = task.validateCount(); err != nil {
+		return fmt.Errorf("%s: %v", task.ID(), err)
+	}
+
+	if task.RecoverAfter < 0 {
+		return fmt.Errorf("%s: parameter 'recoverAfter' must not be negative", task.ID())
+	}
+
+	return nil
+}
+
+// Exec implements Runnable interface
+func (task *InjectNodeFaultTask) Exec(ctx context.Context) error {
+	nodeList, err := task.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("%s: failed to list nodes: %v", task.ID(), err)
+	}
+
+	refNodes, err := task.getRefNodes(task.accessor)
+	if err != nil {
+		return fmt.Errorf("%s: %v", task.ID(), err)
+	}
+
+	nodes := task.selectNodes(nodeList.Items, refNodes, task.rnd)
+	if len(nodes) == 0 {
+		return fmt.Errorf("%s: no nodes matched the selectors", task.ID())
+	}
+
+	// the recovery is registered before the injection, so that the nodes faulted before an error are also recovered
+	var mutex sync.Mutex
+	recoveries := []func(context.Context) error{}
+	names := make([]string, 0, len(nodes))
+
+	recovery := NewRecovery(task.ID(), func(ctx context.Context) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		for i, fn := range recoveries {
+			if err := fn(ctx); err != nil {
+				return fmt.Errorf("failed to recover node %s: %v", names[i], err)
+			}
+		}
+		log.Infof("Recovered %d nodes: %v", len(names), names)
+		return nil
+	})
+	task.recoverer.AddRecovery(task.taskID, recovery)
+
+	for _, node := range nodes {
+		fn, err := task.inject(ctx, node)
+		if err != nil {
+			return fmt.Errorf("%s: failed to inject fault %s into node %s: %v", task.ID(), task.Fault, node.Name, err)
+		}
+		mutex.Lock()
+		recoveries = append(recoveries, fn)
+		names = append(names, node.Name)
+		mutex.Unlock()
+	}
+
+	log.Infof("Injected fault %s into %d nodes: %v", task.Fault, len(names), names)
+
+	if err = task.accessor.SetSelectedNodes(task.taskID, names); err != nil {
+		return err
+	}
+
+	if task.RecoverAfter > 0 {
+		time.AfterFunc(task.RecoverAfter, func() { recovery.Run(context.Background()) })
+	}
+
+	return nil
+}
+
+// inject injects the fault into the node, and returns the function recovering the node
+func (task *InjectNodeFaultTask) inject(ctx context.Context, node *v1.Node) (func(context.Context) error, error) {
+	switch task.Fault {
+	case NodeFaultNotReady:
+		if err := task.setReady(ctx, node.Name, false); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) error { return task.setReady(ctx, node.Name, true) }, nil
+
+	case NodeFaultCordon, NodeFaultDrain:
+		// restore the original value, since the node might have been cordoned before the fault
+		unschedulable := node.Spec.Unschedulable
+		if err := task.setUnschedulable(ctx, node.Name, true); err != nil {
+			return nil, err
+		}
+		if task.Fault == NodeFaultDrain {
+			if err := task.drain(ctx, node.Name); err != nil {
+				return nil, err
+			}
+		}
+		return func(ctx context.Context) error { return task.setUnschedulable(ctx, node.Name, unschedulable) }, nil
+
+	case NodeFaultTaint:
+		if err := task.updateTaints(ctx, node.Name, task.Taints, nil); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) error { return task.updateTaints(ctx, node.Name, nil, task.Taints) }, nil
+
+	case NodeFaultDelete:
+		if err := task.client.CoreV1().Nodes().Delete(ctx, node.Name, metav1.DeleteOptions{}); err != nil {
+			return nil, err
+		}
+		saved := newNodeReplica(node)
+		return func(ctx context.Context) error {
+			_, err := task.client.CoreV1().Nodes().Create(ctx, saved, metav1.CreateOptions{})
+			return err
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported fault %q", task.Fault)
+}
+
+// setReady updates the Ready condition of the node, and the corresponding taints added by the node controller.
+// Note that the node heartbeat in KWOK may restore the Ready condition, while the taints remain.
+func (task *InjectNodeFaultTask) setReady(ctx context.Context, name string, ready bool) error {
+	status, reason, message := v1.ConditionTrue, "KubeletReady", "kubelet is posting ready status"
+	if !ready {
+		status, reason, message = v1.ConditionFalse, "KubeletNotReady", "node failure injected by knavigator"
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{
+					"type":               v1.NodeReady,
+					"status":             status,
+					"reason":             reason,
+					"message":            message,
+					"lastHeartbeatTime":  metav1.Now(),
+					"lastTransitionTime": metav1.Now(),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// conditions are merged by type with the strategic merge patch
+	if _, err = task.client.CoreV1().Nodes().Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}, "status"); err != nil {
+		return err
+	}
+
+	taints := []v1.Taint{
+		{Key: v1.TaintNodeNotReady, Effect: v1.TaintEffectNoSchedule},
+		{Key: v1.TaintNodeNotReady, Effect: v1.TaintEffectNoExecute},
+	}
+	if ready {
+		return task.updateTaints(ctx, name, nil, taints)
+	}
+	return task.updateTaints(ctx, name, taints, nil)
+}
+
+// setUnschedulable cordons or uncordons the node
+func (task *InjectNodeFaultTask) setUnschedulable(ctx context.Context, name string, unschedulable bool) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable))
+	_, err := task.client.CoreV1().Nodes().Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// updateTaints adds and removes the taints, identified by key and effect
+func (task *InjectNodeFaultTask) updateTaints(ctx context.Context, name string, add, remove []v1.Taint) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := task.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		node.Spec.Taints = mergeTaints(node.Spec.Taints, add, remove, metav1.Now())
+
+		_, err = task.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// drain evicts the pods from the node using the Eviction API, skipping DaemonSet and mirror pods
+func (task *InjectNodeFaultTask) drain(ctx context.Context, name string) error {
+	list, err := task.client.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %v", err)
+	}
+
+	for i := range list.Items {
+		pod := &list.Items[i]
+		if !isPodEvictable(pod) {
+			continue
+		}
+		err = task.client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		})
+		switch {
+		case err == nil:
+			log.V(4).Infof("Evicted pod %s/%s from node %s", pod.Namespace, pod.Name, name)
+		case errors.IsNotFound(err):
+			// nop
+		case errors.IsTooManyRequests(err):
+			log.Infof("Warning: eviction of pod %s/%s is blocked by disruption budget", pod.Namespace, pod.Name)
+		default:
+			return fmt.Errorf("failed to evict pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// mergeTaints returns the taints with the added and removed taints, identified by key and effect
+func mergeTaints(taints, add, remove []v1.Taint, now metav1.Time) []v1.Taint {
+	res := []v1.Taint{}
+	for _, taint := range taints {
+		if !hasTaint(add, &taint) && !hasTaint(remove, &taint) {
+			res = append(res, taint)
+		}
+	}
+
+	for _, taint := range add {
+		if taint.Effect == v1.TaintEffectNoExecute && taint.TimeAdded == nil {
+			taint.TimeAdded = &now
+		}
+		res = append(res, taint)
+	}
+
+	return res
+}
+
+func hasTaint(taints []v1.Taint, taint *v1.Taint) bool {
+	for i := range taints {
+		if taints[i].MatchTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPodEvictable returns false for terminated, DaemonSet and mirror pods
+func isPodEvictable(pod *v1.Pod) bool {
+	if !isPodActive(pod) {
+		return false
+	}
+	if _, ok := pod.Annotations[v1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
+
+// newNodeReplica returns a copy of the node suitable for re-creating the deleted node
+func newNodeReplica(node *v1.Node) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        node.Name,
+			Labels:      node.Labels,
+			Annotations: node.Annotations,
+		},
+		Spec: v1.NodeSpec{
+			PodCIDR:    node.Spec.PodCIDR,
+			PodCIDRs:   node.Spec.PodCIDRs,
+			ProviderID: node.Spec.ProviderID,
+			Taints:     node.Spec.Taints,
+		},
+		Status: v1.NodeStatus{
+			Capacity:    node.Status.Capacity,
+			Allocatable: node.Status.Allocatable,
+			Conditions:  node.Status.Conditions,
+			NodeInfo:    node.Status.NodeInfo,
+			Phase:       node.Status.Phase,
+		},
+	}
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+)
+
+func TestInjectNodeFaultParams(t *testing.T) {
+	taskID := "fault"
+	testCases := []struct {
+		name       string
+		simClients bool
+		params     map[string]interface{}
+		err        string
+		task       *InjectNodeFaultTask
+	}{
+		{
+			name:   "Case 1: no k8s client",
+			params: nil,
+			err:    "InjectNodeFault/fault: Kubernetes client is not set",
+		},
+		{
+			name:       "Case 2: missing node selectors",
+			simClients: true,
+			params:     map[string]interface{}{"fault": "cordon"},
+			err:        "InjectNodeFault/fault: missing node selectors",
+		},
+		{
+			name:       "Case 3: invalid fault",
+			simClients: true,
+			params: map[string]interface{}{
+				"selectors": []map[string]string{{"type": "kwok"}},
+				"fault":     "reboot",
+			},
+			err: "InjectNodeFault/fault: invalid fault \"reboot\"; supported: notReady, cordon, drain, taint, delete",
+		},
+		{
+			name:       "Case 4a: taints without taint fault",
+			simClients: true,
+			params: map[string]interface{}{
+				"selectors": []map[string]string{{"type": "kwok"}},
+				"fault":     "drain",
+				"taints":    []map[string]string{{"key": "maintenance", "effect": "NoSchedule"}},
+			},
+			err: "InjectNodeFault/fault: parameter 'taints' is only supported by fault \"taint\"",
+		},
+		{
+			name:       "Case 4b: invalid taint effect",
+			simClients: true,
+			params: map[string]interface{}{
+				"selectors": []map[string]string{{"type": "kwok"}},
+				"fault":     "taint",
+				"taints":    []map[string]string{{"key": "maintenance", "effect": "NoRun"}},
+			},
+			err: "InjectNodeFault/fault: invalid taint effect \"NoRun\"",
+		},
+		{
+			name:       "Case 5: valid parameters",
+			simClients: true,
+			params: map[string]interface{}{
+				"selectors":    []map[string]string{{"type": "kwok"}},
+				"fault":        "taint",
+				"count":        2,
+				"recoverAfter": "5m",
+			},
+			task: &InjectNodeFaultTask{
+				BaseTask: BaseTask{
+					taskType: TaskInjectNodeFault,
+					taskID:   taskID,
+				},
+				injectNodeFaultTaskParams: injectNodeFaultTaskParams{
+					nodeSelection: nodeSelection{
+						Selectors: []map[string]string{{"type": "kwok"}},
+						Count:     "2",
+						number:    2,
+					},
+					Fault:        NodeFaultTaint,
+					Taints:       []v1.Taint{{Key: DefaultFaultTaintKey, Effect: v1.TaintEffectNoExecute}},
+					RecoverAfter: 5 * time.Minute,
+				},
+				client: testK8sClient,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			eng, err := New(nil, nil, tc.simClients)
+			require.NoError(t, err)
+			task, err := eng.GetTask(&config.Task{
+				ID:     taskID,
+				Type:   TaskInjectNodeFault,
+				Params: tc.params,
+			})
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+				require.Nil(t, tc.task)
+			} else {
+				tc.task.recoverer = eng
+				tc.task.accessor = eng
+				require.NoError(t, err)
+				// the random number generator is derived from the workflow seed
+				require.NotNil(t, task.(*InjectNodeFaultTask).rnd)
+				tc.task.rnd = task.(*InjectNodeFaultTask).rnd
+				require.Equal(t, tc.task, task)
+			}
+		})
+	}
+}
+
+func TestMergeTaints(t *testing.T) {
+	now := metav1.Now()
+	gpu := v1.Taint{Key: "nvidia.com/gpu", Effect: v1.TaintEffectNoSchedule}
+	fault := v1.Taint{Key: DefaultFaultTaintKey, Effect: v1.TaintEffectNoExecute}
+	faultAdded := v1.Taint{Key: DefaultFaultTaintKey, Effect: v1.TaintEffectNoExecute, TimeAdded: &now}
+
+	testCases := []struct {
+		name     string
+		taints   []v1.Taint
+		add      []v1.Taint
+		remove   []v1.Taint
+		expected []v1.Taint
+	}{
+		{
+			name:     "Case 1: add taint",
+			taints:   []v1.Taint{gpu},
+			add:      []v1.Taint{fault},
+			expected: []v1.Taint{gpu, faultAdded},
+		},
+		{
+			name:     "Case 2: replace existing taint",
+			taints:   []v1.Taint{faultAdded, gpu},
+			add:      []v1.Taint{fault},
+			expected: []v1.Taint{gpu, faultAdded},
+		},
+		{
+			name:     "Case 3: remove taint",
+			taints:   []v1.Taint{gpu, faultAdded},
+			remove:   []v1.Taint{fault},
+			expected: []v1.Taint{gpu},
+		},
+		{
+			name:     "Case 4: remove missing taint",
+			remove:   []v1.Taint{fault},
+			expected: []v1.Taint{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, mergeTaints(tc.taints, tc.add, tc.remove, now))
+		})
+	}
+}
+
+func TestIsPodEvictable(t *testing.T) {
+	testCases := []struct {
+		name string
+		pod  *v1.Pod
+		res  bool
+	}{
+		{
+			name: "Case 1: running pod",
+			pod:  &v1.Pod{Status: v1.PodStatus{Phase: v1.PodRunning}},
+			res:  true,
+		},
+		{
+			name: "Case 2: completed pod",
+			pod:  &v1.Pod{Status: v1.PodStatus{Phase: v1.PodSucceeded}},
+			res:  false,
+		},
+		{
+			name: "Case 3: mirror pod",
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v1.MirrorPodAnnotationKey: "true"}},
+				Status:     v1.PodStatus{Phase: v1.PodRunning},
+			},
+			res: false,
+		},
+		{
+			name: "Case 4: DaemonSet pod",
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds"}}},
+				Status:     v1.PodStatus{Phase: v1.PodRunning},
+			},
+			res: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.res, isPodEvictable(tc.pod))
+		})
+	}
+}
@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/NVIDIA/knavigator/pkg/utils"
+)
+
+// nodeSelection selects nodes matching any of the label selectors, or the name selector,
+// optionally restricted to the nodes selected by a previous task.
+// Count narrows the matching nodes down to the given number or percentage, either in name order,
+// or randomly; with SpreadBy, the count applies to each group of nodes with the same label value.
+type nodeSelection struct {
+	Selectors []map[string]string `yaml:"selectors"`
+	// Names: an optional node name selector; see utils.NameSelector
+	Names *utils.NameSelector `yaml:"names,omitempty"`
+	// NodesFrom: an optional ID of a previous task; restricts the selection to the nodes chosen by that task
+	NodesFrom string `yaml:"nodesFrom,omitempty"`
+	// Count: an optional number, e.g. "5", or percentage, e.g. "10%", of the matching nodes to select
+	Count string `yaml:"count,omitempty"`
+	// Random: if true, the nodes are selected randomly; requires Count
+	Random bool `yaml:"random,omitempty"`
+	// Seed: an optional seed for random selection; overrides the workflow seed
+	Seed *int64 `yaml:"seed,omitempty"`
+	// SpreadBy: an optional node label key; Count applies to each group of nodes with the same label value
+	SpreadBy string `yaml:"spreadBy,omitempty"`
+
+	// derived
+	number  int
+	percent float64
+}
+
+// isEmpty returns true if no selectors are set
+func (s *nodeSelection) isEmpty() bool {
+	return len(s.Selectors) == 0 && s.Names == nil && len(s.NodesFrom) == 0
+}
+
+// validate validates and initializes the name selector
+func (s *nodeSelection) validate() error {
+	if s.Names != nil {
+		s.Names.Init()
+		return s.Names.Finalize()
+	}
+
+	return nil
+}
+
+// validateCount validates and initializes the count parameters
+func (s *nodeSelection) validateCount() error {
+	if len(s.Count) == 0 {
+		switch {
+		case s.Random:
+			return fmt.Errorf("parameter 'random' requires 'count'")
+		case len(s.SpreadBy) != 0:
+			return fmt.Errorf("parameter 'spreadBy' requires 'count'")
+		case s.Seed != nil:
+			return fmt.Errorf("parameter 'seed' requires 'random'")
+		}
+		return nil
+	}
+
+	if s.Seed != nil && !s.Random {
+		return fmt.Errorf("parameter 'seed' requires 'random'")
+	}
+
+	val := strings.TrimSpace(s.Count)
+	if str, ok := strings.CutSuffix(val, "%"); ok {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return fmt.Errorf("invalid node count %q", s.Count)
+		}
+		s.percent = percent
+		return nil
+	}
+
+	number, err := strconv.Atoi(val)
+	if err != nil || number <= 0 {
+		return fmt.Errorf("invalid node count %q", s.Count)
+	}
+	s.number = number
+
+	return nil
+}
+
+// selectNodes returns the selected nodes.
+// refNodes contains the names of the nodes chosen by the task referred to by NodesFrom, if set.
+// rnd is used for random selection, unless Seed is set.
+func (s *nodeSelection) selectNodes(nodes []v1.Node, refNodes map[string]bool, rnd *rand.Rand) []*v1.Node {
+	var matcher *utils.NameMatcher
+	if s.Names != nil {
+		matcher = s.Names.Matcher()
+	}
+
+	matched := []*v1.Node{}
+	for i := range nodes {
+		node := &nodes[i]
+		if refNodes != nil && !refNodes[node.Name] {
+			continue
+		}
+		if (len(s.Selectors) == 0 && s.Names == nil) || s.isNodeSelected(node, matcher) {
+			matched = append(matched, node)
+		}
+	}
+
+	if len(s.Count) == 0 {
+		return matched
+	}
+
+	// sort the nodes to make the selection reproducible
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Name < matched[j].Name })
+
+	groups := map[string][]*v1.Node{}
+	for _, node := range matched {
+		key := ""
+		if len(s.SpreadBy) != 0 {
+			key = node.Labels[s.SpreadBy]
+		}
+		groups[key] = append(groups[key], node)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	switch {
+	case !s.Random:
+		rnd = nil
+	case s.Seed != nil:
+		rnd = rand.New(rand.NewSource(*s.Seed)) // #nosec G404 // reproducibility is required
+	}
+
+	selected := []*v1.Node{}
+	for _, key := range keys {
+		group := groups[key]
+		n := s.number
+		if s.percent > 0 {
+			n = int(math.Ceil(s.percent * float64(len(group)) / 100))
+		}
+		if n > len(group) {
+			n = len(group)
+		}
+		if rnd != nil {
+			rnd.Shuffle(len(group), func(i, j int) { group[i], group[j] = group[j], group[i] })
+		}
+		selected = append(selected, group[:n]...)
+	}
+
+	return selected
+}
+
+// isNodeSelected returns true if the node matches any of the label selectors, or the name selector
+func (s *nodeSelection) isNodeSelected(node *v1.Node, matcher *utils.NameMatcher) bool {
+	for _, selector := range s.Selectors {
+		if isMapSubset(node.Labels, selector) {
+			return true
+		}
+	}
+
+	return matcher != nil && matcher.IsMatch(node.Name)
+}
+
+// getRefNodes returns the set of the nodes chosen by the task referred to by NodesFrom, or nil if not set
+func (s *nodeSelection) getRefNodes(accessor NodeSelectionAccessor) (map[string]bool, error) {
+	if len(s.NodesFrom) == 0 {
+		return nil, nil
+	}
+
+	names, err := accessor.GetSelectedNodes(s.NodesFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	refNodes := make(map[string]bool)
+	for _, name := range names {
+		refNodes[name] = true
+	}
+
+	return refNodes, nil
+}
+
+// nodeNames returns the names of the nodes
+func nodeNames(nodes []*v1.Node) []string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return names
+}
+
+func isMapSubset(mapSet, mapSubset map[string]string) bool {
+	for key, value := range mapSubset {
+		if v, ok := mapSet[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
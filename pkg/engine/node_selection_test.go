@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeSelectionValidateCount(t *testing.T) {
+	seed := int64(1)
+	testCases := []struct {
+		name    string
+		s       nodeSelection
+		number  int
+		percent float64
+		err     string
+	}{
+		{
+			name: "Case 1: no count",
+		},
+		{
+			name: "Case 2a: random without count",
+			s:    nodeSelection{Random: true},
+			err:  "parameter 'random' requires 'count'",
+		},
+		{
+			name: "Case 2b: spread without count",
+			s:    nodeSelection{SpreadBy: "rack"},
+			err:  "parameter 'spreadBy' requires 'count'",
+		},
+		{
+			name: "Case 2c: seed without random",
+			s:    nodeSelection{Count: "2", Seed: &seed},
+			err:  "parameter 'seed' requires 'random'",
+		},
+		{
+			name: "Case 3a: invalid number",
+			s:    nodeSelection{Count: "0"},
+			err:  "invalid node count \"0\"",
+		},
+		{
+			name: "Case 3b: invalid percentage",
+			s:    nodeSelection{Count: "150%"},
+			err:  "invalid node count \"150%\"",
+		},
+		{
+			name:   "Case 4a: number",
+			s:      nodeSelection{Count: "5", Random: true, Seed: &seed},
+			number: 5,
+		},
+		{
+			name:    "Case 4b: percentage",
+			s:       nodeSelection{Count: "10%", SpreadBy: "rack"},
+			percent: 10,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.s.validateCount()
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.number, tc.s.number)
+				require.Equal(t, tc.percent, tc.s.percent)
+			}
+		})
+	}
+}
+
+func TestNodeSelectionSelectNodes(t *testing.T) {
+	// 3 racks with 4 nodes each, and 2 nodes without rack
+	nodes := []v1.Node{}
+	for i := 11; i >= 0; i-- {
+		nodes = append(nodes, v1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("node-%02d", i),
+			Labels: map[string]string{"type": "kwok", "rack": fmt.Sprintf("r%d", i/4)},
+		}})
+	}
+	for i := 12; i < 14; i++ {
+		nodes = append(nodes, v1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("node-%02d", i),
+			Labels: map[string]string{"type": "kwok"},
+		}})
+	}
+
+	seed := int64(42)
+
+	testCases := []struct {
+		name     string
+		s        nodeSelection
+		refNodes map[string]bool
+		expected []string
+		count    int
+	}{
+		{
+			name:  "Case 1: all matching nodes",
+			s:     nodeSelection{Selectors: []map[string]string{{"rack": "r1"}}},
+			count: 4,
+			expected: []string{
+				"node-07", "node-06", "node-05", "node-04",
+			},
+		},
+		{
+			name:     "Case 2: first nodes in name order",
+			s:        nodeSelection{Selectors: []map[string]string{{"type": "kwok"}}, Count: "3"},
+			count:    3,
+			expected: []string{"node-00", "node-01", "node-02"},
+		},
+		{
+			name:  "Case 3: percentage per rack",
+			s:     nodeSelection{Selectors: []map[string]string{{"type": "kwok"}}, Count: "25%", SpreadBy: "rack"},
+			count: 4,
+			// the nodes without the label form a separate group
+			expected: []string{"node-12", "node-00", "node-04", "node-08"},
+		},
+		{
+			name:  "Case 4: random with seed",
+			s:     nodeSelection{Selectors: []map[string]string{{"type": "kwok"}}, Count: "5", Random: true, Seed: &seed},
+			count: 5,
+		},
+		{
+			name:  "Case 4b: random with workflow seed",
+			s:     nodeSelection{Selectors: []map[string]string{{"type": "kwok"}}, Count: "20%", Random: true},
+			count: 3,
+		},
+		{
+			name:     "Case 5: nodes from previous task",
+			s:        nodeSelection{NodesFrom: "busy"},
+			refNodes: map[string]bool{"node-03": true, "node-13": true},
+			count:    2,
+			expected: []string{"node-03", "node-13"},
+		},
+		{
+			name:     "Case 6: nodes from previous task matching selectors",
+			s:        nodeSelection{NodesFrom: "busy", Selectors: []map[string]string{{"rack": "r0"}}},
+			refNodes: map[string]bool{"node-03": true, "node-13": true},
+			count:    1,
+			expected: []string{"node-03"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.s.validateCount())
+			names := nodeNames(tc.s.selectNodes(nodes, tc.refNodes, rand.New(rand.NewSource(7))))
+			require.Len(t, names, tc.count)
+			if tc.expected != nil {
+				require.Equal(t, tc.expected, names)
+			}
+			if tc.s.Random {
+				// the same seed results in the same selection
+				require.Equal(t, names, nodeNames(tc.s.selectNodes(nodes, tc.refNodes, rand.New(rand.NewSource(7)))))
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 
 	"gopkg.in/yaml.v3"
@@ -42,13 +43,54 @@ func (task *ObjStateTask) validate(params map[string]interface{}) error {
 		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
 	}
 
-	if len(task.RefTaskID) == 0 {
+	return task.validateState()
+}
+
+// validateState validates the object selection, state and assertions
+func (task *ObjStateTask) validateState() error {
+	if task.Target != nil {
+		if len(task.RefTaskID) != 0 {
+			return fmt.Errorf("%s: parameters 'refTaskId' and 'target' are mutually exclusive", task.ID())
+		}
+		if task.Index != 0 {
+			return fmt.Errorf("%s: parameter 'index' is not supported with 'target'", task.ID())
+		}
+		if err := task.Target.validate(); err != nil {
+			return fmt.Errorf("%s: %v", task.ID(), err)
+		}
+	} else if len(task.RefTaskID) == 0 {
 		return fmt.Errorf("%s: missing parameter 'refTaskId'", task.ID())
 	}
 
-	if len(task.State) == 0 {
+	if len(task.State) == 0 && len(task.Assert) == 0 {
 		return fmt.Errorf("%s: missing parameter 'state'", task.ID())
 	}
 
+	if task.Quantifier != nil {
+		if err := task.Quantifier.validate(); err != nil {
+			return fmt.Errorf("%s: %v", task.ID(), err)
+		}
+	}
+
+	for _, a := range task.Assert {
+		if err := a.Finalize(); err != nil {
+			return fmt.Errorf("%s: invalid assertion: %v", task.ID(), err)
+		}
+	}
+
 	return nil
 }
+
+// getObjInfo returns ObjInfo either for the referenced task, or for the objects selected by the target
+func (task *ObjStateTask) getObjInfo(ctx context.Context) (*ObjInfo, error) {
+	if task.Target == nil {
+		return task.accessor.GetObjInfo(task.RefTaskID)
+	}
+
+	info, err := task.Target.resolve(ctx, task.client, task.accessor)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", task.ID(), err)
+	}
+
+	return info, nil
+}
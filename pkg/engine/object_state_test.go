@@ -21,6 +21,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/knavigator/pkg/utils"
 )
 
 func TestObjStateTaskValidate(t *testing.T) {
@@ -70,6 +72,31 @@ func TestObjStateTaskValidate(t *testing.T) {
 				Timeout:   time.Minute,
 			},
 		},
+		{
+			name: "Case 6: invalid assertion",
+			params: map[string]interface{}{
+				"refTaskId": 1,
+				"assert": []interface{}{
+					map[string]interface{}{"path": ".status.active", "op": "~", "value": 1},
+				},
+			},
+			err: "Test/step: invalid assertion: path .status.active: invalid operator \"~\"",
+		},
+		{
+			name: "Case 7: valid input with assertions only",
+			params: map[string]interface{}{
+				"refTaskId": 1,
+				"assert": []interface{}{
+					map[string]interface{}{"path": ".status.active", "op": ">=", "value": 2},
+				},
+			},
+			state: StateParams{
+				RefTaskID: "1",
+				Assert: []*utils.Assertion{
+					{Path: ".status.active", Op: ">=", Value: 2, Match: utils.AssertMatchAny},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -85,6 +112,14 @@ func TestObjStateTaskValidate(t *testing.T) {
 				require.EqualError(t, err, tc.err)
 			} else {
 				require.NoError(t, err)
+				require.Equal(t, len(tc.state.Assert), len(task.Assert))
+				for i, a := range tc.state.Assert {
+					require.Equal(t, a.Path, task.Assert[i].Path)
+					require.Equal(t, a.Op, task.Assert[i].Op)
+					require.Equal(t, a.Value, task.Assert[i].Value)
+					require.Equal(t, a.Match, task.Assert[i].Match)
+				}
+				tc.state.Assert, task.Assert = nil, nil
 				require.Equal(t, tc.state, task.StateParams)
 			}
 		})
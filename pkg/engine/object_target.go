@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	log "k8s.io/klog/v2"
+
+	"github.com/NVIDIA/knavigator/pkg/utils"
+)
+
+// errNoTargetMatch is returned if no objects match the target selectors
+var errNoTargetMatch = errors.New("no objects matched target")
+
+// ObjTarget selects existing objects by type and selectors.
+// It is an alternative to 'refTaskId' for objects not submitted by the workflow,
+// e.g. objects created by controllers.
+type ObjTarget struct {
+	APIVersion    string `yaml:"apiVersion"`
+	Kind          string `yaml:"kind"`
+	Namespace     string `yaml:"namespace,omitempty"`
+	LabelSelector string `yaml:"labelSelector,omitempty"`
+	FieldSelector string `yaml:"fieldSelector,omitempty"`
+	// Names: an optional name selector; see utils.NameSelector
+	Names *utils.NameSelector `yaml:"names,omitempty"`
+}
+
+// validate checks mandatory fields and selector syntax
+func (t *ObjTarget) validate() error {
+	if len(t.APIVersion) == 0 || len(t.Kind) == 0 {
+		return fmt.Errorf("target must specify apiVersion and kind")
+	}
+
+	if len(t.LabelSelector) == 0 && len(t.FieldSelector) == 0 && t.Names == nil {
+		return fmt.Errorf("target must specify labelSelector, fieldSelector and/or names")
+	}
+
+	if t.Names != nil {
+		t.Names.Init()
+		if err := t.Names.Finalize(); err != nil {
+			return fmt.Errorf("invalid target names: %v", err)
+		}
+	}
+
+	if _, err := labels.Parse(t.LabelSelector); err != nil {
+		return fmt.Errorf("invalid target labelSelector %q: %v", t.LabelSelector, err)
+	}
+
+	if _, err := fields.ParseSelector(t.FieldSelector); err != nil {
+		return fmt.Errorf("invalid target fieldSelector %q: %v", t.FieldSelector, err)
+	}
+
+	return nil
+}
+
+// resolve lists objects matching the target and returns the corresponding ObjInfo
+func (t *ObjTarget) resolve(ctx context.Context, client *dynamic.DynamicClient, accessor ObjInfoAccessor) (*ObjInfo, error) {
+	gvk := schema.FromAPIVersionAndKind(t.APIVersion, t.Kind)
+	gvr, namespaced, err := accessor.GetGVR(gvk)
+	if err != nil {
+		return nil, err
+	}
+
+	if namespaced && len(t.Namespace) == 0 {
+		return nil, fmt.Errorf("target must specify namespace for namespaced kind %s", t.Kind)
+	}
+
+	ns := t.Namespace
+	if !namespaced {
+		ns = ""
+	}
+
+	var names []string
+	if t.isNameOnly() {
+		// explicit names do not require listing, and may refer to objects that do not exist yet
+		names = t.Names.Names()
+	} else {
+		list, err := client.Resource(gvr).Namespace(ns).List(ctx, metav1.ListOptions{
+			LabelSelector: t.LabelSelector,
+			FieldSelector: t.FieldSelector,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %v", gvr.Resource, err)
+		}
+
+		var matcher *utils.NameMatcher
+		if t.Names != nil {
+			matcher = t.Names.Matcher()
+		}
+
+		names = make([]string, 0, len(list.Items))
+		for _, item := range list.Items {
+			if matcher == nil || matcher.IsMatch(item.GetName()) {
+				names = append(names, item.GetName())
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: %s", errNoTargetMatch, gvr.Resource)
+	}
+
+	log.V(4).Infof("Target matched %s %v", gvr.Resource, names)
+
+	return NewObjInfo(names, ns, []schema.GroupVersionResource{gvr}, 0), nil
+}
+
+// isNameOnly returns true if the target is defined by the explicit list of names
+func (t *ObjTarget) isNameOnly() bool {
+	return t.Names != nil && len(t.Names.Regexp) == 0 && len(t.LabelSelector) == 0 && len(t.FieldSelector) == 0
+}
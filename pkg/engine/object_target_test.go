@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/knavigator/pkg/utils"
+)
+
+func TestObjTargetValidate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		target   ObjTarget
+		nameOnly bool
+		err      string
+	}{
+		{
+			name:   "Case 1: missing kind",
+			target: ObjTarget{APIVersion: "v1", LabelSelector: "app=test"},
+			err:    "target must specify apiVersion and kind",
+		},
+		{
+			name:   "Case 2: missing selectors",
+			target: ObjTarget{APIVersion: "v1", Kind: "Pod"},
+			err:    "target must specify labelSelector, fieldSelector and/or names",
+		},
+		{
+			name:   "Case 3: invalid label selector",
+			target: ObjTarget{APIVersion: "v1", Kind: "Pod", LabelSelector: "app in (a"},
+			err:    "invalid target labelSelector \"app in (a\": unable to parse requirement: found '', expected: ',' or ')'",
+		},
+		{
+			name:   "Case 4: invalid field selector",
+			target: ObjTarget{APIVersion: "v1", Kind: "Pod", FieldSelector: "status.phase"},
+			err:    "invalid target fieldSelector \"status.phase\": invalid selector: 'status.phase'; can't understand 'status.phase'",
+		},
+		{
+			name: "Case 5: invalid names",
+			target: ObjTarget{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Names:      &utils.NameSelector{List: &utils.NameList{}},
+			},
+			err: "invalid target names: missing patterns in name list",
+		},
+		{
+			name: "Case 6: valid input",
+			target: ObjTarget{
+				APIVersion:    "kueue.x-k8s.io/v1beta1",
+				Kind:          "Workload",
+				Namespace:     "default",
+				LabelSelector: "kueue.x-k8s.io/job-uid",
+				FieldSelector: "metadata.namespace=default",
+			},
+		},
+		{
+			name: "Case 7: valid input with names",
+			target: ObjTarget{
+				APIVersion: "v1",
+				Kind:       "Node",
+				Names: &utils.NameSelector{
+					Range: &utils.NameRange{Pattern: "node-{{._INDEX_}}", Ranges: []string{"1-16", "20"}},
+				},
+			},
+			nameOnly: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.target.validate()
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.nameOnly, tc.target.isNameOnly())
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	log "k8s.io/klog/v2"
+)
+
+// Pod tracking modes; see RegisterObjParams.PodTracking
+const (
+	PodTrackingRegexp = "regexp"
+	PodTrackingOwner  = "owner"
+	PodTrackingLabel  = "label"
+
+	// maxOwnerDepth limits the length of the ownership chain from a pod to a submitted object
+	maxOwnerDepth = 5
+
+	// podSettlePeriod: with unknown pod count, the pod set must stay the same for this period
+	// before the quantifiers depending on the total number of pods are satisfied
+	podSettlePeriod = 10 * time.Second
+)
+
+// podless kinds, by group, do not spawn pods; e.g. pod groups submitted with the workloads
+var podlessKinds = map[string]map[string]bool{
+	"":                      {"ConfigMap": true, "Secret": true, "Service": true, "ServiceAccount": true, "PersistentVolumeClaim": true},
+	"scheduling.x-k8s.io":   {"PodGroup": true},
+	"scheduling.volcano.sh": {"PodGroup": true},
+	"kueue.x-k8s.io":        {"LocalQueue": true, "ClusterQueue": true, "ResourceFlavor": true, "Workload": true},
+}
+
+// pod group references used by gang schedulers
+var podGroupKeys = []string{
+	"scheduling.x-k8s.io/pod-group", // scheduler-plugins coscheduling
+	"scheduling.k8s.io/group-name",  // volcano
+	"pod-group.scheduling.sigs.k8s.io",
+}
+
+// ownerTracker matches pods owned, directly or through intermediate controllers, by the submitted objects
+type ownerTracker struct {
+	client   *dynamic.DynamicClient
+	accessor ObjInfoAccessor
+
+	mutex sync.Mutex
+	// owned: ownership verdict for the objects in the chain, keyed by UID; the submitted objects are owned by definition
+	owned map[types.UID]bool
+	// podGroups: namespace/name of the submitted pod groups
+	podGroups map[string]bool
+}
+
+// newOwnerTracker returns ownerTracker for the objects
+func newOwnerTracker(client *dynamic.DynamicClient, accessor ObjInfoAccessor, info *ObjInfo) *ownerTracker {
+	tracker := &ownerTracker{
+		client:    client,
+		accessor:  accessor,
+		owned:     make(map[types.UID]bool),
+		podGroups: make(map[string]bool),
+	}
+
+	for _, uid := range info.UIDs {
+		tracker.owned[uid] = true
+	}
+	for j, gvr := range info.GVR {
+		if gvr.Resource != "podgroups" {
+			continue
+		}
+		for i, name := range info.Names {
+			tracker.podGroups[info.ObjNamespace(j, i)+"/"+name] = true
+		}
+	}
+
+	return tracker
+}
+
+// isOwned returns true if the pod belongs to the submitted objects
+func (t *ownerTracker) isOwned(ctx context.Context, pod *v1.Pod) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, key := range podGroupKeys {
+		if name, ok := pod.Labels[key]; ok && t.podGroups[pod.Namespace+"/"+name] {
+			return true
+		}
+		if name, ok := pod.Annotations[key]; ok && t.podGroups[pod.Namespace+"/"+name] {
+			return true
+		}
+	}
+
+	return t.isOwnedBy(ctx, pod.Namespace, pod.OwnerReferences, 0)
+}
+
+// isOwnedBy returns true if any of the owners is a submitted object, or is owned by one
+func (t *ownerTracker) isOwnedBy(ctx context.Context, namespace string, refs []metav1.OwnerReference, depth int) bool {
+	if depth >= maxOwnerDepth {
+		return false
+	}
+
+	for _, ref := range refs {
+		owned, ok := t.owned[ref.UID]
+		if !ok {
+			owners, err := t.getOwners(ctx, namespace, ref)
+			if err != nil {
+				// the owner could have been deleted; don't cache the verdict
+				log.V(4).Infof("Failed to get owner %s %s: %v", ref.Kind, ref.Name, err)
+				continue
+			}
+			owned = t.isOwnedBy(ctx, namespace, owners, depth+1)
+			t.owned[ref.UID] = owned
+		}
+		if owned {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getOwners returns the owner references of the object
+func (t *ownerTracker) getOwners(ctx context.Context, namespace string, ref metav1.OwnerReference) ([]metav1.OwnerReference, error) {
+	gvr, namespaced, err := t.accessor.GetGVR(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+	if err != nil {
+		return nil, err
+	}
+	if !namespaced {
+		namespace = ""
+	}
+
+	obj, err := t.client.Resource(gvr).Namespace(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if obj.GetUID() != ref.UID {
+		return nil, fmt.Errorf("object was re-created")
+	}
+
+	return obj.GetOwnerReferences(), nil
+}
+
+// workloadPodCount returns the number of pods the object is expected to spawn, if known.
+// It supports pods, apps controllers, jobs, jobsets, Kubeflow training jobs, and the podless kinds.
+func workloadPodCount(obj *GenericObject) (int, bool) {
+	spec, _ := obj.Spec.(map[string]interface{})
+	group := ""
+	if gv, err := schema.ParseGroupVersion(obj.APIVersion); err == nil {
+		group = gv.Group
+	}
+
+	switch {
+	case podlessKinds[group][obj.Kind]:
+		return 0, true
+	case group == "" && obj.Kind == "Pod":
+		return 1, true
+	case group == "apps" && (obj.Kind == "Deployment" || obj.Kind == "StatefulSet" || obj.Kind == "ReplicaSet"):
+		return specInt(spec, "replicas", 1), true
+	case group == "batch" && obj.Kind == "Job":
+		return jobPodCount(spec), true
+	case group == "jobset.x-k8s.io" && obj.Kind == "JobSet":
+		return jobSetPodCount(spec)
+	case group == "kubeflow.org":
+		return replicaSpecsPodCount(spec)
+	default:
+		return 0, false
+	}
+}
+
+// jobPodCount returns the number of pods running in parallel for the Job spec
+func jobPodCount(spec map[string]interface{}) int {
+	count := specInt(spec, "parallelism", 1)
+	if completions := specInt(spec, "completions", count); completions < count {
+		count = completions
+	}
+	return count
+}
+
+// jobSetPodCount returns the number of pods for the JobSet spec
+func jobSetPodCount(spec map[string]interface{}) (int, bool) {
+	jobs, ok := spec["replicatedJobs"].([]interface{})
+	if !ok {
+		return 0, false
+	}
+
+	count := 0
+	for _, job := range jobs {
+		m, _ := job.(map[string]interface{})
+		tpl, _ := m["template"].(map[string]interface{})
+		jobSpec, _ := tpl["spec"].(map[string]interface{})
+		count += specInt(m, "replicas", 1) * jobPodCount(jobSpec)
+	}
+	return count, true
+}
+
+// replicaSpecsPodCount returns the number of pods for Kubeflow jobs, e.g. "pytorchReplicaSpecs" or "mpiReplicaSpecs"
+func replicaSpecsPodCount(spec map[string]interface{}) (int, bool) {
+	found := false
+	count := 0
+	for key, val := range spec {
+		if !strings.HasSuffix(key, "ReplicaSpecs") {
+			continue
+		}
+		replicaSpecs, ok := val.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		found = true
+		for _, rs := range replicaSpecs {
+			m, _ := rs.(map[string]interface{})
+			count += specInt(m, "replicas", 1)
+		}
+	}
+	return count, found
+}
+
+// specInt returns the integer value of the spec field, or the default value if the field is missing
+func specInt(spec map[string]interface{}, key string, def int) int {
+	switch val := spec[key].(type) {
+	case int:
+		return val
+	case int64:
+		return int(val)
+	case float64:
+		return int(val)
+	default:
+		return def
+	}
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestWorkloadPodCount(t *testing.T) {
+	testCases := []struct {
+		name  string
+		obj   string
+		count int
+		known bool
+	}{
+		{
+			name: "Case 1: pod",
+			obj: `
+apiVersion: v1
+kind: Pod
+spec:
+  containers: []
+`,
+			count: 1,
+			known: true,
+		},
+		{
+			name: "Case 2: deployment with default replicas",
+			obj: `
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  selector: {}
+`,
+			count: 1,
+			known: true,
+		},
+		{
+			name: "Case 3: job limited by completions",
+			obj: `
+apiVersion: batch/v1
+kind: Job
+spec:
+  parallelism: 4
+  completions: 2
+`,
+			count: 2,
+			known: true,
+		},
+		{
+			name: "Case 4: jobset",
+			obj: `
+apiVersion: jobset.x-k8s.io/v1alpha2
+kind: JobSet
+spec:
+  replicatedJobs:
+  - name: workers
+    replicas: 2
+    template:
+      spec:
+        parallelism: 3
+  - name: driver
+    template:
+      spec: {}
+`,
+			count: 7,
+			known: true,
+		},
+		{
+			name: "Case 5: kubeflow job",
+			obj: `
+apiVersion: kubeflow.org/v1
+kind: PyTorchJob
+spec:
+  pytorchReplicaSpecs:
+    Master:
+      replicas: 1
+    Worker:
+      replicas: 3
+`,
+			count: 4,
+			known: true,
+		},
+		{
+			name: "Case 6: podless kind",
+			obj: `
+apiVersion: scheduling.x-k8s.io/v1alpha1
+kind: PodGroup
+spec:
+  minMember: 2
+`,
+			count: 0,
+			known: true,
+		},
+		{
+			name: "Case 7: unknown kind",
+			obj: `
+apiVersion: ray.io/v1
+kind: RayJob
+spec:
+  entrypoint: python main.py
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var obj GenericObject
+			require.NoError(t, yaml.Unmarshal([]byte(tc.obj), &obj))
+			count, known := workloadPodCount(&obj)
+			require.Equal(t, tc.known, known)
+			require.Equal(t, tc.count, count)
+		})
+	}
+}
+
+func TestObjsPodCount(t *testing.T) {
+	parse := func(data string) *GenericObject {
+		var obj GenericObject
+		require.NoError(t, yaml.Unmarshal([]byte(data), &obj))
+		return &obj
+	}
+	job := parse("apiVersion: batch/v1\nkind: Job\nspec:\n  parallelism: 2\n")
+	podGroup := parse("apiVersion: scheduling.x-k8s.io/v1alpha1\nkind: PodGroup\nspec: {}\n")
+	rayJob := parse("apiVersion: ray.io/v1\nkind: RayJob\nspec: {}\n")
+
+	require.Equal(t, 4, objsPodCount([][]*GenericObject{{podGroup, job}, {podGroup, job}}))
+	// the count of the mixed known and unknown kinds is unknown
+	require.Equal(t, 0, objsPodCount([][]*GenericObject{{job, rayJob}}))
+}
+
+func TestOwnerTracker(t *testing.T) {
+	info := NewObjInfo([]string{"job1", "job2"}, "default", []schema.GroupVersionResource{
+		{Group: "scheduling.x-k8s.io", Version: "v1alpha1", Resource: "podgroups"},
+		{Group: "example.com", Version: "v1", Resource: "myobjects"},
+	}, 0)
+	info.UIDs = []types.UID{"pg1", "obj1", "pg2", "obj2"}
+
+	tracker := newOwnerTracker(nil, nil, info)
+	// the verdict for the intermediate controller is cached
+	tracker.owned["job-of-obj2"] = true
+	tracker.owned["other-job"] = false
+
+	testCases := []struct {
+		name  string
+		pod   *v1.Pod
+		owned bool
+	}{
+		{
+			name: "Case 1: owned by submitted object",
+			pod: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "MyObject", Name: "job1", UID: "obj1"}}}},
+			owned: true,
+		},
+		{
+			name: "Case 2: owned through controller",
+			pod: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: "job2-worker", UID: "job-of-obj2"}}}},
+			owned: true,
+		},
+		{
+			name: "Case 3: not owned",
+			pod: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: "other", UID: "other-job"}}}},
+			owned: false,
+		},
+		{
+			name: "Case 4: member of submitted pod group",
+			pod: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default",
+				Labels: map[string]string{"scheduling.x-k8s.io/pod-group": "job2"}}},
+			owned: true,
+		},
+		{
+			name: "Case 5: member of pod group in another namespace",
+			pod: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "test",
+				Annotations: map[string]string{"scheduling.k8s.io/group-name": "job2"}}},
+			owned: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.owned, tracker.isOwned(context.TODO(), tc.pod))
+		})
+	}
+}
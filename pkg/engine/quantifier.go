@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	QuantifierAll     = "all"
+	QuantifierAny     = "any"
+	QuantifierNone    = "none"
+	QuantifierAtLeast = "atLeast"
+	QuantifierAtMost  = "atMost"
+	QuantifierExactly = "exactly"
+)
+
+// Quantifier defines how many objects in a batch must satisfy the predicate.
+// Type is one of "all" (default), "any", "none", "atLeast", "atMost", "exactly".
+// Value is required for "atLeast", "atMost", "exactly", and contains either
+// the number of objects, e.g. "3", or the percentage of objects, e.g. "80%".
+type Quantifier struct {
+	Type  string `yaml:"type"`
+	Value string `yaml:"value,omitempty"`
+
+	// derived
+	number  int
+	percent float64
+}
+
+// validate validates and initializes Quantifier
+func (q *Quantifier) validate() error {
+	switch q.Type {
+	case QuantifierAll, QuantifierAny, QuantifierNone:
+		if len(q.Value) != 0 {
+			return fmt.Errorf("quantifier %q does not accept value", q.Type)
+		}
+		return nil
+	case QuantifierAtLeast, QuantifierAtMost, QuantifierExactly:
+		// nop
+	default:
+		return fmt.Errorf("invalid quantifier %q; supported: %s, %s, %s, %s, %s, %s", q.Type,
+			QuantifierAll, QuantifierAny, QuantifierNone, QuantifierAtLeast, QuantifierAtMost, QuantifierExactly)
+	}
+
+	val := strings.TrimSpace(q.Value)
+	if len(val) == 0 {
+		return fmt.Errorf("quantifier %q requires value", q.Type)
+	}
+
+	if str, ok := strings.CutSuffix(val, "%"); ok {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return fmt.Errorf("invalid percentage %q in quantifier %q", q.Value, q.Type)
+		}
+		q.percent = percent
+		return nil
+	}
+
+	number, err := strconv.Atoi(val)
+	if err != nil || number < 0 {
+		return fmt.Errorf("invalid value %q in quantifier %q", q.Value, q.Type)
+	}
+	q.number = number
+
+	return nil
+}
+
+// isSatisfied returns true if the number of objects satisfying the predicate conforms to the quantifier
+func (q *Quantifier) isSatisfied(matched, total int) bool {
+	if q == nil {
+		return matched == total
+	}
+
+	switch q.Type {
+	case QuantifierAny:
+		return matched > 0
+	case QuantifierNone:
+		return matched == 0
+	case QuantifierAtLeast:
+		return matched >= q.threshold(total, math.Ceil)
+	case QuantifierAtMost:
+		return matched <= q.threshold(total, math.Floor)
+	case QuantifierExactly:
+		return matched == q.threshold(total, math.Round)
+	default:
+		return matched == total
+	}
+}
+
+// isAll returns true for the default "all" quantifier
+func (q *Quantifier) isAll() bool {
+	return q == nil || q.Type == QuantifierAll
+}
+
+// isMonotone returns true if the quantifier, once satisfied, stays satisfied as more objects are found,
+// so that it can be evaluated without knowing the total number of objects
+func (q *Quantifier) isMonotone() bool {
+	if q == nil {
+		return false
+	}
+	return q.Type == QuantifierAny || (q.Type == QuantifierAtLeast && !strings.HasSuffix(strings.TrimSpace(q.Value), "%"))
+}
+
+// threshold returns the number of objects, converting percentage with the rounding function
+func (q *Quantifier) threshold(total int, round func(float64) float64) int {
+	if strings.HasSuffix(strings.TrimSpace(q.Value), "%") {
+		return int(round(q.percent * float64(total) / 100))
+	}
+	return q.number
+}
+
+// String implements Stringer interface
+func (q *Quantifier) String() string {
+	if q == nil {
+		return QuantifierAll
+	}
+	if len(q.Value) == 0 {
+		return q.Type
+	}
+	return q.Type + " " + q.Value
+}
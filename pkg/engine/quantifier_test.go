@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuantifier(t *testing.T) {
+	testCases := []struct {
+		name  string
+		q     *Quantifier
+		total int
+		valid []int
+		err   string
+	}{
+		{
+			name:  "Case 1: default",
+			total: 5,
+			valid: []int{5},
+		},
+		{
+			name: "Case 2a: invalid type",
+			q:    &Quantifier{Type: "most"},
+			err:  `invalid quantifier "most"; supported: all, any, none, atLeast, atMost, exactly`,
+		},
+		{
+			name: "Case 2b: unexpected value",
+			q:    &Quantifier{Type: QuantifierAny, Value: "2"},
+			err:  `quantifier "any" does not accept value`,
+		},
+		{
+			name: "Case 2c: missing value",
+			q:    &Quantifier{Type: QuantifierAtLeast},
+			err:  `quantifier "atLeast" requires value`,
+		},
+		{
+			name: "Case 2d: invalid percentage",
+			q:    &Quantifier{Type: QuantifierAtMost, Value: "120%"},
+			err:  `invalid percentage "120%" in quantifier "atMost"`,
+		},
+		{
+			name: "Case 2e: invalid number",
+			q:    &Quantifier{Type: QuantifierExactly, Value: "-1"},
+			err:  `invalid value "-1" in quantifier "exactly"`,
+		},
+		{
+			name:  "Case 3: all",
+			q:     &Quantifier{Type: QuantifierAll},
+			total: 3,
+			valid: []int{3},
+		},
+		{
+			name:  "Case 4: any",
+			q:     &Quantifier{Type: QuantifierAny},
+			total: 3,
+			valid: []int{1, 2, 3},
+		},
+		{
+			name:  "Case 5: none",
+			q:     &Quantifier{Type: QuantifierNone},
+			total: 3,
+			valid: []int{0},
+		},
+		{
+			name:  "Case 6a: at least number",
+			q:     &Quantifier{Type: QuantifierAtLeast, Value: "2"},
+			total: 3,
+			valid: []int{2, 3},
+		},
+		{
+			name:  "Case 6b: at least percentage",
+			q:     &Quantifier{Type: QuantifierAtLeast, Value: "80%"},
+			total: 9,
+			valid: []int{8, 9},
+		},
+		{
+			name:  "Case 7a: at most number",
+			q:     &Quantifier{Type: QuantifierAtMost, Value: "1"},
+			total: 3,
+			valid: []int{0, 1},
+		},
+		{
+			name:  "Case 7b: at most percentage",
+			q:     &Quantifier{Type: QuantifierAtMost, Value: "25%"},
+			total: 10,
+			valid: []int{0, 1, 2},
+		},
+		{
+			name:  "Case 8: exactly",
+			q:     &Quantifier{Type: QuantifierExactly, Value: "3"},
+			total: 10,
+			valid: []int{3},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.q != nil {
+				err := tc.q.validate()
+				if len(tc.err) != 0 {
+					require.EqualError(t, err, tc.err)
+					return
+				}
+				require.NoError(t, err)
+			}
+
+			valid := []int{}
+			for n := 0; n <= tc.total; n++ {
+				if tc.q.isSatisfied(n, tc.total) {
+					valid = append(valid, n)
+				}
+			}
+			require.Equal(t, tc.valid, valid)
+		})
+	}
+}
+
+func TestQuantifierIsMonotone(t *testing.T) {
+	require.False(t, (*Quantifier)(nil).isMonotone())
+	require.False(t, (&Quantifier{Type: QuantifierAll}).isMonotone())
+	require.True(t, (&Quantifier{Type: QuantifierAny}).isMonotone())
+	require.True(t, (&Quantifier{Type: QuantifierAtLeast, Value: "2"}).isMonotone())
+	require.False(t, (&Quantifier{Type: QuantifierAtLeast, Value: "50%"}).isMonotone())
+	require.False(t, (&Quantifier{Type: QuantifierExactly, Value: "2"}).isMonotone())
+}
@@ -17,21 +17,20 @@
 package engine
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"fmt"
-	"os"
 	"regexp"
 	"strings"
 	"text/template"
 
 	"gopkg.in/yaml.v3"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	log "k8s.io/klog/v2"
 
 	"github.com/NVIDIA/knavigator/pkg/config"
+	"github.com/NVIDIA/knavigator/pkg/utils"
 )
 
 var reDelim *regexp.Regexp
@@ -51,7 +50,9 @@ func init() {
 }
 
 // newRegisterObjTask initializes and returns RegisterObjTask
-func newRegisterObjTask(client *discovery.DiscoveryClient, accessor ObjInfoAccessor, cfg *config.Task) (*RegisterObjTask, error) {
+// templates contains the template files bundled with the workflow, keyed by the template reference.
+func newRegisterObjTask(client *discovery.DiscoveryClient, accessor ObjInfoAccessor, funcs template.FuncMap,
+	templates map[string]string, cfg *config.Task) (*RegisterObjTask, error) {
 	if client == nil {
 		return nil, fmt.Errorf("%s/%s: DiscoveryClient is not set", cfg.Type, cfg.ID)
 	}
@@ -65,15 +66,16 @@ func newRegisterObjTask(client *discovery.DiscoveryClient, accessor ObjInfoAcces
 		accessor: accessor,
 	}
 
-	if err := task.validate(cfg.Params); err != nil {
+	if err := task.validate(cfg.Params, templates, funcs); err != nil {
 		return nil, err
 	}
 
 	return task, nil
 }
 
-// validate initializes and validates parameters for RegisterObjTask.
-func (task *RegisterObjTask) validate(params map[string]interface{}) error {
+// validate initializes and validates parameters for RegisterObjTask,
+// and parses the templates with the template functions.
+func (task *RegisterObjTask) validate(params map[string]interface{}, templates map[string]string, funcs template.FuncMap) error {
 	data, err := yaml.Marshal(params)
 	if err != nil {
 		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
@@ -86,63 +88,71 @@ func (task *RegisterObjTask) validate(params map[string]interface{}) error {
 		return fmt.Errorf("%s: must specify template", task.ID())
 	}
 
-	tplData, err := os.ReadFile(task.Template)
+	if err = utils.ValidateMissingKey(task.MissingKey); err != nil {
+		return fmt.Errorf("%s: %v", task.ID(), err)
+	}
+
+	tplStr, err := task.loadTemplate(templates)
 	if err != nil {
-		return fmt.Errorf("%s: failed to read %s: %v", task.ID(), task.Template, err)
+		return fmt.Errorf("%s: failed to read %s: %v", task.ID(), task.templateName(), err)
 	}
 
-	tplStr := string(tplData)
 	task.gvk = []schema.GroupVersionKind{}
 	task.objTpl = []*template.Template{}
 
 	blocks := reDelim.Split(tplStr, -1)
 	for _, block := range blocks {
-		var ver, kind string
-		scanner := bufio.NewScanner(strings.NewReader(block))
-		for scanner.Scan() {
-			line := scanner.Text()
-			if strings.HasPrefix(line, "apiVersion:") {
-				ver = strings.TrimSpace(line[11:])
-			}
-			if strings.HasPrefix(line, "kind:") {
-				kind = strings.TrimSpace(line[5:])
-			}
-			if len(ver) != 0 && len(kind) != 0 {
-				break
-			}
-		}
-		if err := scanner.Err(); err != nil {
-			return fmt.Errorf("%s: failed to process template %s: %v", task.ID(), task.Template, err)
-		}
-		if len(ver) == 0 {
-			return fmt.Errorf("%s: failed to fetch 'apiVersion' from template %s", task.ID(), task.Template)
+		if len(strings.TrimSpace(block)) == 0 {
+			continue
 		}
-		if len(kind) == 0 {
-			return fmt.Errorf("%s: failed to fetch 'kind' from template %s", task.ID(), task.Template)
+		objTpl, err := utils.ParseTemplate(fmt.Sprintf("object%d", len(task.objTpl)), block, funcs, task.MissingKey)
+		if err != nil {
+			return fmt.Errorf("%s: failed to parse template %s: %v", task.ID(), task.templateName(), err)
 		}
 
-		gvk := schema.FromAPIVersionAndKind(ver, kind)
+		gvk, err := task.templateGVK(objTpl, block)
+		if err != nil {
+			return fmt.Errorf("%s: %v from template %s", task.ID(), err, task.templateName())
+		}
 		log.Infof("Register %s", gvk.String())
 		task.gvk = append(task.gvk, gvk)
+		task.objTpl = append(task.objTpl, objTpl)
+	}
 
-		objTpl, err := template.New(gvk.String()).Parse(block)
-		if err != nil {
-			return fmt.Errorf("%s: failed to parse template %s: %v", task.ID(), task.Template, err)
+	if len(task.NameFormat) != 0 {
+		if task.nameTpl, err = utils.ParseTemplate("name", task.NameFormat, funcs, task.MissingKey); err != nil {
+			return fmt.Errorf("%s: failed to parse name template: %v", task.ID(), err)
 		}
-		task.objTpl = append(task.objTpl, objTpl)
 	}
 
 	if len(task.PodNameFormat) != 0 {
-		if task.podNameTpl, err = template.New("podname").Parse(task.PodNameFormat); err != nil {
+		if task.podNameTpl, err = utils.ParseTemplate("podname", task.PodNameFormat, funcs, task.MissingKey); err != nil {
 			return fmt.Errorf("%s: failed to parse podname template: %v", task.ID(), err)
 		}
 	}
 
+	switch task.PodTracking {
+	case "", PodTrackingRegexp:
+	case PodTrackingOwner, PodTrackingLabel:
+		if task.podNameTpl != nil {
+			return fmt.Errorf("%s: podNameFormat is not used with %q pod tracking", task.ID(), task.PodTracking)
+		}
+		if len(task.PodCount) != 0 {
+			if task.podCountTpl, err = utils.ParseTemplate("podcount", task.PodCount, funcs, task.MissingKey); err != nil {
+				return fmt.Errorf("%s: failed to parse podcount template: %v", task.ID(), err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s: invalid podTracking %q; supported: %s, %s, %s",
+			task.ID(), task.PodTracking, PodTrackingRegexp, PodTrackingOwner, PodTrackingLabel)
+	}
+
 	if len(task.PodCount) != 0 {
 		if task.podNameTpl == nil {
 			return fmt.Errorf("%s: must define podNameFormat with podCount", task.ID())
 		}
-		if task.podCountTpl, err = template.New("podcount").Parse(task.PodCount); err != nil {
+		if task.podCountTpl, err = utils.ParseTemplate("podcount", task.PodCount, funcs, task.MissingKey); err != nil {
 			return fmt.Errorf("%s: failed to parse podcount template: %v", task.ID(), err)
 		}
 	} else if task.podNameTpl != nil {
@@ -152,41 +162,99 @@ func (task *RegisterObjTask) validate(params map[string]interface{}) error {
 	return nil
 }
 
-// Exec implements Runnable interface
-func (task *RegisterObjTask) Exec(ctx context.Context) error {
-	apiResourceList, err := task.client.ServerPreferredResources()
-	if err != nil {
-		return fmt.Errorf("%s: failed to retrieve API resources: %v", task.ID(), err)
+// loadTemplate returns the inline template, or the template bundled with the workflow,
+// or the content of the template files on the local filesystem
+func (task *RegisterObjTask) loadTemplate(templates map[string]string) (string, error) {
+	if data, ok := templates[task.Template]; ok {
+		return data, nil
 	}
+	return config.LoadTemplate(task.Template)
+}
 
-	task.gvr = make([]schema.GroupVersionResource, 0, len(task.gvk))
+// templateName returns the template reference for the log and error messages
+func (task *RegisterObjTask) templateName() string {
+	if config.IsInlineTemplate(task.Template) {
+		return "inline template"
+	}
+	return task.Template
+}
 
-	for _, gvk := range task.gvk {
-		switch gvk.String() {
-		case "batch/v1, Kind=Job":
-			task.gvr = append(task.gvr, schema.GroupVersionResource{
-				Group:    gvk.Group,
-				Version:  gvk.Version,
-				Resource: "jobs",
-			})
-		default:
-			if err := task.getGVR(apiResourceList, gvk); err != nil {
-				return err
+// templateGVK derives the object kind by rendering the template with sample parameters and parsing the result.
+// If the template cannot be rendered without the actual parameters, it parses the top-level fields of the raw template.
+func (task *RegisterObjTask) templateGVK(tpl *template.Template, block string) (schema.GroupVersionKind, error) {
+	sample := map[string]interface{}{
+		"_NAME_":  "sample",
+		"_ENUM_":  0,
+		"_INDEX_": 0,
+		"_TASK_":  task.taskID,
+	}
+
+	var meta TypeMeta
+	if tpl, err := tpl.Clone(); err == nil {
+		buf := new(bytes.Buffer)
+		if err = tpl.Option("missingkey=zero").Execute(buf, sample); err == nil {
+			if err = yaml.Unmarshal(buf.Bytes(), &meta); err != nil {
+				log.V(4).Infof("%s: failed to parse rendered template: %v", task.ID(), err)
 			}
+		} else {
+			log.V(4).Infof("%s: failed to render template with sample parameters: %v", task.ID(), err)
 		}
 	}
-	return task.accessor.SetObjType(task.taskID, &task.RegisterObjParams)
+
+	if !isTypeMetaValid(meta.APIVersion) || !isTypeMetaValid(meta.Kind) {
+		meta = scanTypeMeta(block)
+	}
+
+	if !isTypeMetaValid(meta.APIVersion) {
+		return schema.GroupVersionKind{}, fmt.Errorf("failed to fetch 'apiVersion'")
+	}
+	if !isTypeMetaValid(meta.Kind) {
+		return schema.GroupVersionKind{}, fmt.Errorf("failed to fetch 'kind'")
+	}
+
+	return schema.FromAPIVersionAndKind(meta.APIVersion, meta.Kind), nil
 }
 
-func (task *RegisterObjTask) getGVR(apiResourceList []*v1.APIResourceList, gvk schema.GroupVersionKind) error {
-	for _, list := range apiResourceList {
-		for _, r := range list.APIResources {
-			if r.Group == gvk.Group && r.Kind == gvk.Kind {
-				task.gvr = append(task.gvr, schema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Name})
-				return nil
-			}
+// scanTypeMeta parses the top-level 'apiVersion' and 'kind' fields of the raw template
+func scanTypeMeta(block string) TypeMeta {
+	var meta TypeMeta
+	for _, line := range strings.Split(block, "\n") {
+		// skip nested fields, comments and template actions
+		if len(line) == 0 || line[0] == ' ' || line[0] == '\t' || line[0] == '#' || strings.HasPrefix(line, "{{") {
+			continue
+		}
+		var field TypeMeta
+		if err := yaml.Unmarshal([]byte(line), &field); err != nil {
+			continue
+		}
+		if len(field.APIVersion) != 0 {
+			meta.APIVersion = field.APIVersion
+		}
+		if len(field.Kind) != 0 {
+			meta.Kind = field.Kind
 		}
 	}
+	return meta
+}
+
+// isTypeMetaValid returns true if the field is set and does not depend on the template parameters
+func isTypeMetaValid(val string) bool {
+	return len(val) != 0 && !strings.Contains(val, "<no value>") && !strings.Contains(val, "{{")
+}
+
+// Exec implements Runnable interface
+func (task *RegisterObjTask) Exec(_ context.Context) error {
+	task.gvr = make([]schema.GroupVersionResource, 0, len(task.gvk))
+	task.namespaced = make([]bool, 0, len(task.gvk))
 
-	return fmt.Errorf("%s: failed to find resource for %s", task.ID(), gvk.String())
+	for _, gvk := range task.gvk {
+		gvr, namespaced, err := task.accessor.GetGVR(gvk)
+		if err != nil {
+			return fmt.Errorf("%s: %v", task.ID(), err)
+		}
+		task.gvr = append(task.gvr, gvr)
+		task.namespaced = append(task.namespaced, namespaced)
+	}
+
+	return task.accessor.SetObjType(task.taskID, &task.RegisterObjParams)
 }
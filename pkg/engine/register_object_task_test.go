@@ -17,12 +17,15 @@
 package engine
 
 import (
+	"context"
 	"testing"
+	"text/template"
 
 	"github.com/stretchr/testify/require"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/NVIDIA/knavigator/pkg/config"
+	"github.com/NVIDIA/knavigator/pkg/utils"
 )
 
 func TestNewRegisterObjTask(t *testing.T) {
@@ -31,6 +34,7 @@ func TestNewRegisterObjTask(t *testing.T) {
 		name       string
 		params     map[string]interface{}
 		simClients bool
+		templates  map[string]string
 		err        string
 		task       *RegisterObjTask
 		pods       []string
@@ -96,6 +100,44 @@ func TestNewRegisterObjTask(t *testing.T) {
 			simClients: true,
 			err:        "RegisterObj/register: must define podNameFormat with podCount",
 		},
+		{
+			name: "Case 8a: invalid podTracking",
+			params: map[string]interface{}{
+				"template":    "../../resources/templates/example.yml",
+				"podTracking": "name",
+			},
+			simClients: true,
+			err:        "RegisterObj/register: invalid podTracking \"name\"; supported: regexp, owner, label",
+		},
+		{
+			name: "Case 8aa: podNameFormat with owner tracking",
+			params: map[string]interface{}{
+				"template":      "../../resources/templates/example.yml",
+				"podTracking":   "owner",
+				"podNameFormat": "test{{._NAME_}}",
+				"podCount":      "2",
+			},
+			simClients: true,
+			err:        "RegisterObj/register: podNameFormat is not used with \"owner\" pod tracking",
+		},
+		{
+			name: "Case 8b: invalid missingKey",
+			params: map[string]interface{}{
+				"template":   "../../resources/templates/example.yml",
+				"missingKey": "fail",
+			},
+			simClients: true,
+			err:        "RegisterObj/register: invalid missingKey \"fail\"; supported: default, zero, error",
+		},
+		{
+			name: "Case 8c: unknown template function",
+			params: map[string]interface{}{
+				"template":   "../../resources/templates/example.yml",
+				"nameFormat": "{{ unknown ._ENUM_ }}",
+			},
+			simClients: true,
+			err:        "RegisterObj/register: failed to parse name template: template: name:1: function \"unknown\" not defined",
+		},
 		{
 			name: "Case 9: valid input",
 			params: map[string]interface{}{
@@ -126,14 +168,57 @@ func TestNewRegisterObjTask(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Case 10: inline template",
+			params: map[string]interface{}{
+				"template": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: \"{{._NAME_}}\"\n---\napiVersion: batch/v1\nkind: Job\nmetadata:\n  name: \"{{._NAME_}}\"\n",
+			},
+			simClients: true,
+			task: &RegisterObjTask{
+				BaseTask: BaseTask{
+					taskType: TaskRegisterObj,
+					taskID:   taskID,
+				},
+				RegisterObjParams: RegisterObjParams{
+					Template: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: \"{{._NAME_}}\"\n---\napiVersion: batch/v1\nkind: Job\nmetadata:\n  name: \"{{._NAME_}}\"\n",
+				},
+				client: testDiscoveryClient,
+				gvk: []schema.GroupVersionKind{
+					{Version: "v1", Kind: "ConfigMap"},
+					{Group: "batch", Version: "v1", Kind: "Job"},
+				},
+			},
+		},
+		{
+			name: "Case 11: template bundled with workflow",
+			params: map[string]interface{}{
+				"template": "/remote/job.yaml",
+			},
+			templates:  map[string]string{"/remote/job.yaml": "---\napiVersion: batch/v1\nkind: Job\n"},
+			simClients: true,
+			task: &RegisterObjTask{
+				BaseTask: BaseTask{
+					taskType: TaskRegisterObj,
+					taskID:   taskID,
+				},
+				RegisterObjParams: RegisterObjParams{
+					Template: "/remote/job.yaml",
+				},
+				client: testDiscoveryClient,
+				gvk: []schema.GroupVersionKind{
+					{Group: "batch", Version: "v1", Kind: "Job"},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			eng, err := New(nil, nil, tc.simClients)
 			require.NoError(t, err)
+			ctx := withTemplates(context.Background(), tc.templates)
 
-			runnable, err := eng.GetTask(&config.Task{
+			runnable, err := eng.getTask(ctx, &config.Task{
 				ID:     taskID,
 				Type:   TaskRegisterObj,
 				Params: tc.params,
@@ -147,9 +232,99 @@ func TestNewRegisterObjTask(t *testing.T) {
 				tc.task.accessor = eng
 
 				task := runnable.(*RegisterObjTask)
-				task.objTpl, task.podNameTpl, task.podCountTpl = nil, nil, nil
+				task.nameTpl, task.objTpl, task.podNameTpl, task.podCountTpl = nil, nil, nil, nil
 				require.Equal(t, tc.task, task)
 			}
 		})
 	}
 }
+
+func TestTemplateGVK(t *testing.T) {
+	testCases := []struct {
+		name  string
+		block string
+		gvk   schema.GroupVersionKind
+		err   string
+	}{
+		{
+			name:  "Case 1: quoted fields with comments",
+			block: "# comment\nkind: \"Job\" # job\napiVersion: 'batch/v1'\nmetadata:\n  name: {{._NAME_}}\n",
+			gvk:   schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"},
+		},
+		{
+			name: "Case 2: nested object first",
+			block: `spec:
+  template:
+    apiVersion: v1
+    kind: Pod
+apiVersion: batch/v1
+kind: Job
+`,
+			gvk: schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"},
+		},
+		{
+			name: "Case 3: template actions that fail without parameters",
+			block: `apiVersion: v1
+kind: ConfigMap
+data:
+  key: {{ index .labels "key" }}
+`,
+			gvk: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+		},
+		{
+			name:  "Case 4: templated kind",
+			block: "apiVersion: v1\nkind: {{.kind}}\n",
+			err:   "failed to fetch 'kind'",
+		},
+		{
+			name:  "Case 5: missing apiVersion",
+			block: "kind: Job\n",
+			err:   "failed to fetch 'apiVersion'",
+		},
+	}
+
+	task := &RegisterObjTask{BaseTask: BaseTask{taskType: TaskRegisterObj, taskID: "register"}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := template.New("test").Parse(tc.block)
+			require.NoError(t, err)
+
+			gvk, err := task.templateGVK(tpl, tc.block)
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.gvk, gvk)
+			}
+		})
+	}
+}
+
+func TestRegisterObjRemoteEnv(t *testing.T) {
+	t.Setenv("KNAVIGATOR_TEST_ENV", "value")
+
+	eng, err := New(nil, nil, true)
+	require.NoError(t, err)
+
+	cfg := &config.Task{
+		ID:   "register",
+		Type: TaskRegisterObj,
+		Params: map[string]interface{}{
+			"template":   "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: \"{{._NAME_}}\"\n",
+			"nameFormat": `{{ env "KNAVIGATOR_TEST_ENV" }}`,
+		},
+	}
+
+	// the local workflows read the environment
+	runnable, err := eng.getTask(context.Background(), cfg)
+	require.NoError(t, err)
+	name, err := utils.ExecTemplate(runnable.(*RegisterObjTask).nameTpl, nil)
+	require.NoError(t, err)
+	require.Equal(t, `"value"`, string(name))
+
+	// the remote workflows do not
+	runnable, err = eng.getTask(WithRemoteWorkflow(context.Background()), cfg)
+	require.NoError(t, err)
+	_, err = utils.ExecTemplate(runnable.(*RegisterObjTask).nameTpl, nil)
+	require.ErrorContains(t, err, "env is disabled for remote workflows")
+}
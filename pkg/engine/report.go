@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// maxReportItems limits the number of items printed in each section of a failure report
+const maxReportItems = 20
+
+// podReport describes the state of the checked pods when CheckPod validation fails
+type podReport struct {
+	expected int
+	found    int
+	// phases: number of pods per phase
+	phases map[string]int
+	// missing: names of expected pods that were not found; empty if the names are unknown
+	missing []string
+	// mislabeled: pods running on nodes without the expected node labels
+	mislabeled []string
+	// events: the latest FailedScheduling event message per pending pod
+	events map[string]string
+}
+
+// newPodReport counts the pods per phase and finds missing pods, if the expected pod names are known
+func newPodReport(expected int, names []string, pods []*v1.Pod) *podReport {
+	r := &podReport{
+		expected: expected,
+		found:    len(pods),
+		phases:   make(map[string]int),
+		events:   make(map[string]string),
+	}
+
+	found := make(map[string]bool)
+	for _, pod := range pods {
+		r.phases[string(pod.Status.Phase)]++
+		found[pod.Name] = true
+	}
+
+	for _, name := range names {
+		if !found[name] {
+			r.missing = append(r.missing, name)
+		}
+	}
+
+	return r
+}
+
+// String implements Stringer interface
+func (r *podReport) String() string {
+	var sb strings.Builder
+
+	phases := make([]string, 0, len(r.phases))
+	for phase, n := range r.phases {
+		phases = append(phases, fmt.Sprintf("%s: %d", phase, n))
+	}
+	sort.Strings(phases)
+	fmt.Fprintf(&sb, "pods: expected %d, found %d (%s)", r.expected, r.found, strings.Join(phases, ", "))
+
+	switch {
+	case len(r.missing) != 0:
+		writeReportSection(&sb, "missing pods", r.missing)
+	case r.found < r.expected:
+		fmt.Fprintf(&sb, "\nmissing pods: %d", r.expected-r.found)
+	}
+
+	writeReportSection(&sb, "pods on nodes without expected labels", r.mislabeled)
+
+	events := make([]string, 0, len(r.events))
+	for pod, msg := range r.events {
+		events = append(events, fmt.Sprintf("%s: %s", pod, msg))
+	}
+	sort.Strings(events)
+	writeReportSection(&sb, "FailedScheduling events for pending pods", events)
+
+	return sb.String()
+}
+
+// objReport describes the state of the checked objects when CheckObj validation fails
+type objReport struct {
+	resource  string
+	expected  int
+	satisfied int
+	// failed: the reason of the failure per object
+	failed map[string]string
+}
+
+// String implements Stringer interface
+func (r *objReport) String() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s: expected %d, satisfied %d", r.resource, r.expected, r.satisfied)
+
+	failed := make([]string, 0, len(r.failed))
+	for name, reason := range r.failed {
+		failed = append(failed, fmt.Sprintf("%s: %s", name, reason))
+	}
+	sort.Strings(failed)
+	writeReportSection(&sb, "unsatisfied "+r.resource, failed)
+
+	return sb.String()
+}
+
+// writeReportSection prints the section title followed by up to maxReportItems items
+func writeReportSection(sb *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+
+	fmt.Fprintf(sb, "\n%s (%d):", title, len(items))
+	for i, item := range items {
+		if i == maxReportItems {
+			fmt.Fprintf(sb, "\n  ... and %d more", len(items)-maxReportItems)
+			break
+		}
+		fmt.Fprintf(sb, "\n  %s", item)
+	}
+}
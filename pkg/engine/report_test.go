@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodReport(t *testing.T) {
+	newPod := func(name string, phase v1.PodPhase) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+	}
+
+	pods := []*v1.Pod{
+		newPod("pod-1", v1.PodRunning),
+		newPod("pod-2", v1.PodRunning),
+		newPod("pod-3", v1.PodPending),
+	}
+
+	testCases := []struct {
+		name   string
+		names  []string
+		update func(*podReport)
+		report string
+	}{
+		{
+			name:   "Case 1: unknown pod names",
+			report: "pods: expected 4, found 3 (Pending: 1, Running: 2)\nmissing pods: 1",
+		},
+		{
+			name:   "Case 2: known pod names",
+			names:  []string{"pod-1", "pod-2", "pod-3", "pod-4"},
+			report: "pods: expected 4, found 3 (Pending: 1, Running: 2)\nmissing pods (1):\n  pod-4",
+		},
+		{
+			name: "Case 3: labels and events",
+			update: func(r *podReport) {
+				r.mislabeled = []string{"pod-2 on node-1: missing rack=r1"}
+				r.events["pod-3"] = "0/2 nodes are available: 2 Insufficient nvidia.com/gpu."
+			},
+			report: "pods: expected 4, found 3 (Pending: 1, Running: 2)\nmissing pods: 1" +
+				"\npods on nodes without expected labels (1):\n  pod-2 on node-1: missing rack=r1" +
+				"\nFailedScheduling events for pending pods (1):\n  pod-3: 0/2 nodes are available: 2 Insufficient nvidia.com/gpu.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newPodReport(4, tc.names, pods)
+			if tc.update != nil {
+				tc.update(r)
+			}
+			require.Equal(t, tc.report, r.String())
+		})
+	}
+}
+
+func TestObjReport(t *testing.T) {
+	failed := make(map[string]string)
+	for i := 1; i <= maxReportItems+2; i++ {
+		failed[fmt.Sprintf("job-%02d", i)] = "state mismatch"
+	}
+
+	r := &objReport{
+		resource:  "jobs",
+		expected:  maxReportItems + 5,
+		satisfied: 3,
+		failed:    failed,
+	}
+
+	expected := fmt.Sprintf("jobs: expected %d, satisfied 3\nunsatisfied jobs (%d):", maxReportItems+5, maxReportItems+2)
+	for i := 1; i <= maxReportItems; i++ {
+		expected += fmt.Sprintf("\n  job-%02d: state mismatch", i)
+	}
+	expected += "\n  ... and 2 more"
+
+	require.Equal(t, expected, r.String())
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+)
+
+// RestoreTask restores the cluster state changed by the referenced task before the workflow ends,
+// e.g. the state recorded by Configure task with 'restore: true', or the nodes faulted by InjectNodeFault task
+// with 'recoverAfter'. The state is restored once: Reset does not restore it again.
+type RestoreTask struct {
+	BaseTask
+	restoreTaskParams
+
+	recoverer Recoverer
+}
+
+type restoreTaskParams struct {
+	// RefTaskID: ID of the task that changed the cluster state
+	RefTaskID string `yaml:"refTaskId"`
+}
+
+// newRestoreTask initializes and returns RestoreTask
+func newRestoreTask(recoverer Recoverer, cfg *config.Task) (*RestoreTask, error) {
+	task := &RestoreTask{
+		BaseTask: BaseTask{
+			taskType: TaskRestore,
+			taskID:   cfg.ID,
+		},
+		recoverer: recoverer,
+	}
+
+	if err := task.validate(cfg.Params); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// validate initializes and validates parameters for RestoreTask
+func (task *RestoreTask) validate(params map[string]interface{}) error {
+	data, err := yaml.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
+	}
+	if err = yaml.Unmarshal(data, &task.restoreTaskParams); err != nil {
+		return fmt.Errorf("%s: failed to parse parameters: %v", task.ID(), err)
+	}
+
+	if len(task.RefTaskID) == 0 {
+		return fmt.Errorf("%s: missing parameter 'refTaskId'", task.ID())
+	}
+
+	return nil
+}
+
+// Exec implements Runnable interface
+func (task *RestoreTask) Exec(ctx context.Context) error {
+	r, err := task.recoverer.GetRecovery(task.RefTaskID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", task.ID(), err)
+	}
+
+	if err = r.Exec(ctx); err != nil {
+		return fmt.Errorf("%s: %v", task.ID(), err)
+	}
+
+	return nil
+}
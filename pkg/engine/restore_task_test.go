@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/knavigator/pkg/config"
+)
+
+func TestRestoreTask(t *testing.T) {
+	taskID := "restore"
+	testCases := []struct {
+		name      string
+		params    map[string]interface{}
+		refTaskID string
+		err       string
+	}{
+		{
+			name: "Case 1: missing refTaskId",
+			err:  "Restore/restore: missing parameter 'refTaskId'",
+		},
+		{
+			name:   "Case 2: missing task reference",
+			params: map[string]interface{}{"refTaskId": "configure"},
+			err:    "Restore/restore: unreferenced task ID configure",
+		},
+		{
+			name:      "Case 3: valid parameters",
+			params:    map[string]interface{}{"refTaskId": "configure"},
+			refTaskID: "configure",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			eng, err := New(nil, nil, true)
+			require.NoError(t, err)
+
+			var calls int
+			if len(tc.refTaskID) != 0 {
+				eng.AddRecovery(tc.refTaskID, NewRecovery("Configure/"+tc.refTaskID, func(context.Context) error {
+					calls++
+					return fmt.Errorf("failed")
+				}))
+			}
+
+			task, err := eng.GetTask(&config.Task{
+				ID:     taskID,
+				Type:   TaskRestore,
+				Params: tc.params,
+			})
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			// the recovery runs once, and the reset does not run it again
+			require.EqualError(t, task.Exec(context.Background()), "Restore/restore: failed")
+			require.NoError(t, eng.Reset(context.Background()))
+			require.Equal(t, 1, calls)
+		})
+	}
+}
@@ -19,12 +19,14 @@ package engine
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/maja42/goval"
 	"gopkg.in/yaml.v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	log "k8s.io/klog/v2"
 
@@ -37,6 +39,8 @@ type SubmitObjTask struct {
 	submitObjTaskParams
 	client   *dynamic.DynamicClient
 	accessor ObjInfoAccessor
+	// counter: the default object ID counter, used if the task runs outside of a workflow run
+	counter *utils.Counter
 }
 
 type submitObjTaskParams struct {
@@ -64,7 +68,7 @@ type GenericObject struct {
 }
 
 // newSubmitObjTask initializes and returns SubmitObjTask
-func newSubmitObjTask(client *dynamic.DynamicClient, accessor ObjInfoAccessor, cfg *config.Task) (*SubmitObjTask, error) {
+func newSubmitObjTask(client *dynamic.DynamicClient, accessor ObjInfoAccessor, counter *utils.Counter, cfg *config.Task) (*SubmitObjTask, error) {
 	if client == nil {
 		return nil, fmt.Errorf("%s/%s: DynamicClient is not set", cfg.Type, cfg.ID)
 	}
@@ -76,6 +80,7 @@ func newSubmitObjTask(client *dynamic.DynamicClient, accessor ObjInfoAccessor, c
 		},
 		client:   client,
 		accessor: accessor,
+		counter:  counter,
 	}
 
 	if err := task.validate(cfg.Params); err != nil {
@@ -119,13 +124,26 @@ func (task *SubmitObjTask) Exec(ctx context.Context) error {
 		return fmt.Errorf("%s: multi-instance objects must specify 'nameFormat' during object registration", task.ID())
 	}
 
-	objs, names, podCount, podRegexp, err := task.getGenericObjects(regObjParams)
+	objs, names, podCount, podRegexp, err := task.getGenericObjects(regObjParams, objCounterFrom(ctx, task.counter))
 	if err != nil {
 		return err
 	}
 
+	labels := task.trackingLabels(runIDFrom(ctx))
+	for i, arr := range objs {
+		for _, obj := range arr {
+			setTrackingLabels(obj, labels, i)
+		}
+	}
+
+	uids := []types.UID{}
 	for _, arr := range objs {
 		for i, obj := range arr {
+			// cluster-scoped objects have no namespace; the scope is resolved by RegisterObj task
+			if !regObjParams.isNamespaced(i) {
+				obj.Metadata.Namespace = ""
+			}
+
 			crd := &unstructured.Unstructured{
 				Object: map[string]interface{}{
 					"apiVersion": obj.APIVersion,
@@ -143,19 +161,47 @@ func (task *SubmitObjTask) Exec(ctx context.Context) error {
 				}
 			}
 
-			if _, err := task.client.Resource(regObjParams.gvr[i]).Namespace(obj.Metadata.Namespace).Create(ctx, crd, metav1.CreateOptions{}); err != nil {
+			created, err := task.client.Resource(regObjParams.gvr[i]).Namespace(obj.Metadata.Namespace).Create(ctx, crd, metav1.CreateOptions{})
+			if err != nil {
 				return fmt.Errorf("%s: failed to create resource %s %s: %v",
 					task.ID(), regObjParams.gvr[i].String(), crd.GetName(), err)
 			}
+			uids = append(uids, created.GetUID())
 		}
 	}
 
-	return task.accessor.SetObjInfo(task.taskID,
-		NewObjInfo(names, objs[0][0].Metadata.Namespace, regObjParams.gvr, podCount, podRegexp...))
+	info := NewObjInfo(names, objNamespace(objs[0], regObjParams), regObjParams.gvr, podCount, podRegexp...)
+	info.PodTracking = regObjParams.PodTracking
+	info.UIDs = uids
+	info.Labels = labels
+	info.Namespaces = make([][]string, len(regObjParams.gvr))
+	for j := range info.Namespaces {
+		info.Namespaces[j] = make([]string, len(objs))
+		for i := range objs {
+			info.Namespaces[j][i] = objs[i][j].Metadata.Namespace
+		}
+	}
+
+	return task.accessor.SetObjInfo(task.taskID, info)
 }
 
-func (task *SubmitObjTask) getGenericObjects(regObjParams *RegisterObjParams) ([][]*GenericObject, []string, int, []string, error) {
-	names, err := utils.GenerateNames(regObjParams.NameFormat, task.Count, task.Params)
+// objNamespace returns the namespace of the first namespaced object in the template
+func objNamespace(objs []*GenericObject, regObjParams *RegisterObjParams) string {
+	for j, obj := range objs {
+		if regObjParams.isNamespaced(j) {
+			return obj.Metadata.Namespace
+		}
+	}
+	return ""
+}
+
+func (task *SubmitObjTask) getGenericObjects(regObjParams *RegisterObjParams, counter *utils.Counter) ([][]*GenericObject, []string, int, []string, error) {
+	if task.Params == nil {
+		task.Params = make(map[string]interface{})
+	}
+	task.Params["_TASK_"] = task.taskID
+
+	names, err := utils.GenerateNames(regObjParams.nameTpl, task.Count, counter, task.Params)
 	if err != nil {
 		return nil, nil, 0, nil, fmt.Errorf("%s: failed to generate object names: %v", task.ID(), err)
 	}
@@ -164,6 +210,7 @@ func (task *SubmitObjTask) getGenericObjects(regObjParams *RegisterObjParams) ([
 	podRegexp := []string{}
 
 	for i := 0; i < task.Count; i++ {
+		task.Params["_INDEX_"] = i
 		if len(names[i]) != 0 {
 			task.Params["_NAME_"] = names[i]
 		}
@@ -207,12 +254,77 @@ func (task *SubmitObjTask) getGenericObjects(regObjParams *RegisterObjParams) ([
 		}
 
 		podCount *= task.Count
+	} else if regObjParams.PodTracking == PodTrackingOwner || regObjParams.PodTracking == PodTrackingLabel {
+		podCount = objsPodCount(objs)
 	}
 	log.V(4).Infof("Generating object specs; podCount:%d podRegexp:%v", podCount, podRegexp)
 
 	return objs, names, podCount, podRegexp, nil
 }
 
+// setTrackingLabels sets the tracking labels and the object index in the object metadata and in its pod templates
+func setTrackingLabels(obj *GenericObject, labels map[string]string, index int) {
+	idx := strconv.Itoa(index)
+	if obj.Metadata.Labels == nil {
+		obj.Metadata.Labels = make(map[string]*string)
+	}
+	for key, val := range labels {
+		obj.Metadata.Labels[key] = &val
+	}
+	obj.Metadata.Labels[LabelIndex] = &idx
+
+	setPodTemplateLabels(obj.Spec, labels, idx)
+}
+
+// setPodTemplateLabels sets the tracking labels in the pod templates nested in the object spec,
+// such as "spec.template" of Jobs, or "spec.replicatedJobs[*].template.spec.template" of JobSets
+func setPodTemplateLabels(val interface{}, labels map[string]string, index string) {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		if spec, ok := v["spec"].(map[string]interface{}); ok {
+			if _, ok = spec["containers"].([]interface{}); ok {
+				metadata, ok := v["metadata"].(map[string]interface{})
+				if !ok {
+					metadata = make(map[string]interface{})
+					v["metadata"] = metadata
+				}
+				podLabels, ok := metadata["labels"].(map[string]interface{})
+				if !ok {
+					podLabels = make(map[string]interface{})
+					metadata["labels"] = podLabels
+				}
+				for key, val := range labels {
+					podLabels[key] = val
+				}
+				podLabels[LabelIndex] = index
+			}
+		}
+		for _, child := range v {
+			setPodTemplateLabels(child, labels, index)
+		}
+	case []interface{}:
+		for _, child := range v {
+			setPodTemplateLabels(child, labels, index)
+		}
+	}
+}
+
+// objsPodCount returns the number of pods the objects are expected to spawn,
+// or zero if the pod count of any object is unknown
+func objsPodCount(objs [][]*GenericObject) int {
+	count := 0
+	for _, arr := range objs {
+		for _, obj := range arr {
+			n, ok := workloadPodCount(obj)
+			if !ok {
+				return 0
+			}
+			count += n
+		}
+	}
+	return count
+}
+
 func (obj *GenericObject) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var o struct {
 		TypeMeta `yaml:",inline"`
@@ -21,6 +21,7 @@ import (
 	"text/template"
 
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 
 	"github.com/NVIDIA/knavigator/pkg/config"
 	"github.com/NVIDIA/knavigator/pkg/utils"
@@ -308,8 +309,6 @@ func TestNewSubmitObjTask(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			utils.SetObjectID(0)
-
 			eng, err := New(nil, nil, tc.simClients)
 			require.NoError(t, err)
 
@@ -327,6 +326,7 @@ func TestNewSubmitObjTask(t *testing.T) {
 				require.Nil(t, tc.task)
 			} else {
 				tc.task.accessor = eng
+				tc.task.counter = eng.objCounter
 				require.NoError(t, err)
 				require.NotNil(t, tc.task)
 
@@ -340,6 +340,11 @@ func TestNewSubmitObjTask(t *testing.T) {
 				tc.regObjParams.objTpl[0], err = template.ParseFiles(tc.regObjParams.Template)
 				require.NoError(t, err)
 
+				if len(tc.regObjParams.NameFormat) != 0 {
+					tc.regObjParams.nameTpl, err = template.New("name").Parse(tc.regObjParams.NameFormat)
+					require.NoError(t, err)
+				}
+
 				if len(tc.regObjParams.PodNameFormat) != 0 {
 					tc.regObjParams.podNameTpl, err = template.New("podname").Parse(tc.regObjParams.PodNameFormat)
 					require.NoError(t, err)
@@ -350,7 +355,7 @@ func TestNewSubmitObjTask(t *testing.T) {
 					require.NoError(t, err)
 				}
 
-				objs, names, podCount, podRegexp, err := task.getGenericObjects(tc.regObjParams)
+				objs, names, podCount, podRegexp, err := task.getGenericObjects(tc.regObjParams, utils.NewCounter(0))
 				require.NoError(t, err)
 				require.Equal(t, tc.objs, objs)
 				require.Equal(t, tc.names, names)
@@ -360,3 +365,82 @@ func TestNewSubmitObjTask(t *testing.T) {
 		})
 	}
 }
+
+func TestGetGenericObjectsTaskID(t *testing.T) {
+	nameTpl, err := utils.ParseTemplate("name", "{{._TASK_}}-{{._ENUM_}}", nil, "")
+	require.NoError(t, err)
+	objTpl, err := utils.ParseTemplate("object0", `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: "{{._NAME_}}"
+  labels:
+    task: "{{._TASK_}}"
+`, nil, "")
+	require.NoError(t, err)
+
+	task := &SubmitObjTask{
+		BaseTask:            BaseTask{taskType: TaskSubmitObj, taskID: "job"},
+		submitObjTaskParams: submitObjTaskParams{Count: 2},
+	}
+	regObjParams := &RegisterObjParams{nameTpl: nameTpl, objTpl: []*template.Template{objTpl}}
+
+	objs, names, _, _, err := task.getGenericObjects(regObjParams, utils.NewCounter(0))
+	require.NoError(t, err)
+	require.Equal(t, []string{"job-1", "job-2"}, names)
+	require.Equal(t, "job-1", objs[0][0].Metadata.Name)
+	require.Equal(t, "job", *objs[0][0].Metadata.Labels["task"])
+}
+
+func TestObjNamespace(t *testing.T) {
+	objs := []*GenericObject{
+		{Metadata: objectMeta{Name: "queue"}},
+		{Metadata: objectMeta{Name: "job", Namespace: "team"}},
+	}
+
+	require.Equal(t, "team", objNamespace(objs, &RegisterObjParams{namespaced: []bool{false, true}}))
+	require.Equal(t, "", objNamespace(objs, &RegisterObjParams{namespaced: []bool{true, true}}))
+	require.Equal(t, "", objNamespace(objs, &RegisterObjParams{namespaced: []bool{false, false}}))
+}
+
+func TestSetTrackingLabels(t *testing.T) {
+	var obj GenericObject
+	require.NoError(t, yaml.Unmarshal([]byte(`
+apiVersion: jobset.x-k8s.io/v1alpha2
+kind: JobSet
+metadata:
+  name: jobset1
+  labels:
+    app: test
+spec:
+  replicatedJobs:
+  - name: workers
+    template:
+      spec:
+        template:
+          spec:
+            containers:
+            - name: test
+`), &obj))
+
+	task := &SubmitObjTask{BaseTask: BaseTask{taskType: TaskSubmitObj, taskID: "submit"}}
+	labels := task.trackingLabels("run1")
+	require.Equal(t, map[string]string{LabelRunID: "run1", LabelTaskID: "submit"}, labels)
+
+	setTrackingLabels(&obj, labels, 2)
+
+	objLabels := make(map[string]string)
+	for key, val := range obj.Metadata.Labels {
+		objLabels[key] = *val
+	}
+	require.Equal(t, map[string]string{"app": "test", LabelRunID: "run1", LabelTaskID: "submit", LabelIndex: "2"}, objLabels)
+
+	podTemplate := obj.Spec.(map[string]interface{})["replicatedJobs"].([]interface{})[0].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["template"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"labels": map[string]interface{}{LabelRunID: "run1", LabelTaskID: "submit", LabelIndex: "2"},
+	}, podTemplate["metadata"])
+
+	// invalid label values are skipped
+	task.taskID = "submit/1"
+	require.Equal(t, map[string]string{}, task.trackingLabels(""))
+}
@@ -19,24 +19,38 @@ package engine
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"text/template"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	log "k8s.io/klog/v2"
+
+	"github.com/NVIDIA/knavigator/pkg/utils"
 )
 
 const (
-	TaskConfigure      = "Configure"
-	TaskRegisterObj    = "RegisterObj"
-	TaskSubmitObj      = "SubmitObj"
-	TaskUpdateObj      = "UpdateObj"
-	TaskCheckObj       = "CheckObj"
-	TaskCheckConfigmap = "CheckConfigmap"
-	TaskDeleteObj      = "DeleteObj"
-	TaskCheckPod       = "CheckPod"
-	TaskUpdateNodes    = "UpdateNodes"
-	TaskSleep          = "Sleep"
-	TaskPause          = "Pause"
+	TaskConfigure          = "Configure"
+	TaskConfigureScheduler = "ConfigureScheduler"
+	TaskRegisterObj        = "RegisterObj"
+	TaskSubmitObj          = "SubmitObj"
+	TaskUpdateObj          = "UpdateObj"
+	TaskCheckObj           = "CheckObj"
+	TaskCheckConfigmap     = "CheckConfigmap"
+	TaskCheckResource      = "CheckResource"
+	TaskDeleteObj          = "DeleteObj"
+	TaskCheckPod           = "CheckPod"
+	TaskCheckEvents        = "CheckEvents"
+	TaskInjectFault        = "InjectFault"
+	TaskInjectNodeFault    = "InjectNodeFault"
+	TaskUpdateNodes        = "UpdateNodes"
+	TaskSleep              = "Sleep"
+	TaskPause              = "Pause"
+	TaskRestore            = "Restore"
 
 	OpCreate    = "create"
 	OpDelete    = "delete"
@@ -44,6 +58,13 @@ const (
 	OpCmpSubset = "subset"
 
 	DefaultCleanupTimeout = 5 * time.Minute
+
+	// tracking labels of the submitted objects and their pod templates
+	LabelRunID  = "knavigator.io/run-id"
+	LabelTaskID = "knavigator.io/task-id"
+	LabelIndex  = "knavigator.io/index"
+	// LabelConfigured marks the objects created by Configure tasks, which are cleaned up on request only
+	LabelConfigured = "knavigator.io/configured"
 )
 
 type Runnable interface {
@@ -61,14 +82,34 @@ func (t *BaseTask) ID() string {
 	return fmt.Sprintf("%s/%s", t.taskType, t.taskID)
 }
 
+// trackingLabels returns the tracking labels common to the objects created by the task
+func (t *BaseTask) trackingLabels(runID string) map[string]string {
+	labels := make(map[string]string)
+	if len(runID) != 0 {
+		labels[LabelRunID] = runID
+	}
+	if errs := validation.IsValidLabelValue(t.taskID); len(errs) == 0 {
+		labels[LabelTaskID] = t.taskID
+	} else {
+		log.Warningf("%s: task ID is not a valid label value, skipping %s label: %s", t.ID(), LabelTaskID, strings.Join(errs, "; "))
+	}
+	return labels
+}
+
 type StateParams struct {
 	// RefTaskID is the ID for the task from which the object was submitted
 	RefTaskID string `yaml:"refTaskId"`
+	// Target selects existing objects by type and selectors; mutually exclusive with RefTaskID
+	Target *ObjTarget `yaml:"target,omitempty"`
 	// Index refers to the position of the object within the template file,
 	// or it defaults to zero if the template file contains only a single object.
-	Index   int                    `yaml:"index,omitempty"`
-	State   map[string]interface{} `yaml:"state"`
-	Timeout time.Duration          `yaml:"timeout"`
+	Index int                    `yaml:"index,omitempty"`
+	State map[string]interface{} `yaml:"state"`
+	// Assert is an optional list of assertions evaluated per object, in addition to State
+	Assert []*utils.Assertion `yaml:"assert,omitempty"`
+	// Quantifier defines how many objects must satisfy State and Assert; default is "all"
+	Quantifier *Quantifier   `yaml:"quantifier,omitempty"`
+	Timeout    time.Duration `yaml:"timeout"`
 }
 
 type TypeMeta struct {
@@ -80,8 +121,9 @@ type RegisterObjParams struct {
 	// Template: path to the object template; see examples in resources/templates/
 	Template string `yaml:"template"`
 	// NameFormat: a Go-template parameter for generating unique object names.
-	// It utilizes the '_ENUM_' keyword for an incrementing counter and
-	// adds the '_NAME_' key to the parameter map with the templated value.
+	// It utilizes the '_ENUM_' keyword for a counter incrementing within the workflow run,
+	// the '_INDEX_' keyword for the index of the object in the SubmitObj batch, and the '_TASK_' keyword
+	// for the SubmitObj task ID. It adds the '_NAME_' key to the parameter map with the templated value.
 	// Example: "job{{._ENUM_}}"
 	NameFormat string `yaml:"nameFormat"`
 	// PodNameFormat: an optional Go-template parameter for specifying regexp for the naming format
@@ -94,21 +136,50 @@ type RegisterObjParams struct {
 	// PodCount should be specified when a user intends to use 'CheckPod' task.
 	// Example: "2" or "{{.replicas}}"
 	PodCount string `yaml:"podCount,omitempty"`
+	// PodTracking: an optional mode of finding pods spawned by the object(s) for 'CheckPod' task:
+	// "regexp" (default) matches pod names against PodNameFormat;
+	// "owner" follows pod ownerReferences, through intermediate controllers such as Jobs, up to the submitted objects,
+	// and matches pods referring to the submitted PodGroups;
+	// "label" matches pods by the tracking labels, which SubmitObj task sets in the pod templates of the objects.
+	// With "owner" and "label", PodNameFormat is not used, and PodCount
+	// is optional: if omitted, it is derived from the object spec for Pods, Deployments, StatefulSets, ReplicaSets,
+	// Jobs, JobSets and Kubeflow jobs, or else it is the number of pods found.
+	PodTracking string `yaml:"podTracking,omitempty"`
+	// MissingKey: an optional "missingkey" option for the templates: "default", "zero" or "error".
+	// With "error", the templates fail on undefined parameters instead of rendering "<no value>".
+	MissingKey string `yaml:"missingKey,omitempty"`
 
 	// derived
 	gvr         []schema.GroupVersionResource
+	namespaced  []bool
+	nameTpl     *template.Template
 	objTpl      []*template.Template
 	podNameTpl  *template.Template
 	podCountTpl *template.Template
 }
 
+// isNamespaced returns true if the i-th object in the template is namespaced
+func (p *RegisterObjParams) isNamespaced(i int) bool {
+	return i >= len(p.namespaced) || p.namespaced[i]
+}
+
 // ObjInfo contains object GVR and an optional list of derived pod names
 type ObjInfo struct {
-	Names     []string
+	Names []string
+	// Namespace: the default namespace of the objects, and the namespace of their pods
 	Namespace string
 	GVR       []schema.GroupVersionResource
-	PodCount  int
-	PodRegexp []string
+	// Namespaces: an optional namespace of each object, where Namespaces[j][i] is the namespace of Names[i] of GVR[j].
+	// The namespace of cluster-scoped objects is empty. If not set, all objects are in Namespace.
+	Namespaces [][]string
+	PodCount   int
+	PodRegexp  []string
+	// PodTracking: the pod tracking mode; see RegisterObjParams.PodTracking
+	PodTracking string
+	// UIDs: UIDs of the submitted objects, used by the "owner" pod tracking
+	UIDs []types.UID
+	// Labels: the tracking labels common to the submitted objects, used by the "label" pod tracking
+	Labels map[string]string
 }
 
 // NewObjInfo creates new ObjInfo
@@ -122,6 +193,26 @@ func NewObjInfo(names []string, ns string, gvr []schema.GroupVersionResource, po
 	}
 }
 
+// ObjNamespace returns the namespace of the object with the given GVR and name indexes
+func (info *ObjInfo) ObjNamespace(gvrIndex, nameIndex int) string {
+	if gvrIndex < len(info.Namespaces) && nameIndex < len(info.Namespaces[gvrIndex]) {
+		return info.Namespaces[gvrIndex][nameIndex]
+	}
+	return info.Namespace
+}
+
+// WatchNamespace returns the namespace to watch the objects with the given GVR index:
+// the common namespace of the objects, or all namespaces if the objects span namespaces
+func (info *ObjInfo) WatchNamespace(gvrIndex int) string {
+	ns := info.ObjNamespace(gvrIndex, 0)
+	for i := range info.Names {
+		if info.ObjNamespace(gvrIndex, i) != ns {
+			return metav1.NamespaceAll
+		}
+	}
+	return ns
+}
+
 // ObjInfoAccessor defines interface for getting and setting object info
 type ObjInfoAccessor interface {
 	// SetObjType maps object type to RegisterObjParams
@@ -132,10 +223,81 @@ type ObjInfoAccessor interface {
 	SetObjInfo(string, *ObjInfo) error
 	// GetObjInfo returns ObjInfo for given task ID
 	GetObjInfo(string) (*ObjInfo, error)
+	// GetGVR returns GroupVersionResource for given GroupVersionKind, and whether the resource is namespaced
+	GetGVR(schema.GroupVersionKind) (schema.GroupVersionResource, bool, error)
+}
+
+// NodeSelectionAccessor defines interface for recording and retrieving the nodes chosen by the tasks
+type NodeSelectionAccessor interface {
+	// SetSelectedNodes records names of the nodes chosen by the task
+	SetSelectedNodes(string, []string) error
+	// GetSelectedNodes returns names of the nodes chosen by the task
+	GetSelectedNodes(string) ([]string, error)
+}
+
+// Recovery restores the cluster state changed by a task, e.g. recovers a failed node.
+// It runs once, either when scheduled by the task, or when the engine is reset, whichever comes first.
+type Recovery struct {
+	name string
+	once sync.Once
+	fn   func(context.Context) error
+	err  error
+}
+
+// NewRecovery creates new Recovery
+func NewRecovery(name string, fn func(context.Context) error) *Recovery {
+	return &Recovery{
+		name: name,
+		fn:   fn,
+	}
+}
+
+// Exec executes the recovery if it has not been executed yet, and returns the result of the execution
+func (r *Recovery) Exec(ctx context.Context) error {
+	r.once.Do(func() {
+		log.Infof("Run recovery for %s", r.name)
+		if r.err = r.fn(ctx); r.err != nil {
+			log.Errorf("Recovery for %s failed: %v", r.name, r.err)
+		}
+	})
+	return r.err
+}
+
+// Run executes the recovery if it has not been executed yet; the failure is logged
+func (r *Recovery) Run(ctx context.Context) {
+	_ = r.Exec(ctx)
+}
+
+// Recoverer registers recoveries to run when the engine is reset, or when requested by Restore task
+type Recoverer interface {
+	// AddRecovery registers the recovery of the task with the given ID
+	AddRecovery(string, *Recovery)
+	// GetRecovery returns the recovery of the task with the given ID
+	GetRecovery(string) (*Recovery, error)
 }
 
 // CleanupInfo contains instructions on whether and how to clean up data after the test
 type CleanupInfo struct {
 	Enabled bool
 	Timeout time.Duration
+	// Wait: wait until the objects, including their finalizers and pods, are deleted;
+	// the wait is limited by Timeout, separately from the deletion
+	Wait bool
+	// PropagationPolicy: deletion propagation policy: "Foreground", "Background" (default) or "Orphan"
+	PropagationPolicy string
+}
+
+// Validate validates the cleanup instructions
+func (c *CleanupInfo) Validate() error {
+	_, err := parsePropagationPolicy(c.PropagationPolicy)
+	return err
+}
+
+// propagation returns the deletion propagation policy
+func (c *CleanupInfo) propagation() metav1.DeletionPropagation {
+	policy, err := parsePropagationPolicy(c.PropagationPolicy)
+	if err != nil {
+		return metav1.DeletePropagationBackground
+	}
+	return policy
 }
@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestObjInfoNamespaces(t *testing.T) {
+	gvr := []schema.GroupVersionResource{
+		{Group: "kueue.x-k8s.io", Version: "v1beta1", Resource: "clusterqueues"},
+		{Group: "batch", Version: "v1", Resource: "jobs"},
+	}
+
+	// all objects in the default namespace
+	info := NewObjInfo([]string{"a", "b"}, "default", gvr, 0)
+	require.Equal(t, "default", info.ObjNamespace(1, 1))
+	require.Equal(t, "default", info.WatchNamespace(1))
+
+	// cluster-scoped objects, and namespaced objects spanning namespaces
+	info.Namespaces = [][]string{{"", ""}, {"ns1", "ns2"}}
+	require.Equal(t, "", info.ObjNamespace(0, 1))
+	require.Equal(t, "ns2", info.ObjNamespace(1, 1))
+	require.Equal(t, "", info.WatchNamespace(0))
+	require.Equal(t, "", info.WatchNamespace(1))
+
+	info.Namespaces[1][1] = "ns1"
+	require.Equal(t, "ns1", info.WatchNamespace(1))
+}
@@ -19,33 +19,37 @@ package engine
 import (
 	"context"
 	"fmt"
+	"math/rand"
 
 	"gopkg.in/yaml.v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	log "k8s.io/klog/v2"
 
 	"github.com/NVIDIA/knavigator/pkg/config"
 	"github.com/NVIDIA/knavigator/pkg/utils"
 )
 
 // UpdateNodesTask represents UpdateNodes task.
-// This task sets labels specified in params.Labels to the nodes specified in params.Selector
+// This task applies the state specified in params.State to the nodes specified
+// in params.Selectors and/or params.Names, and records the names of the updated nodes.
 type UpdateNodesTask struct {
 	BaseTask
 	nodeStateParams
 
-	client *kubernetes.Clientset
+	client   *kubernetes.Clientset
+	accessor NodeSelectionAccessor
+	rnd      *rand.Rand
 }
 
 // nodeStateParams contains parameters set by the user.
 type nodeStateParams struct {
-	StateParams `yaml:",inline"`
-
-	Selectors []map[string]string `yaml:"selectors"`
+	StateParams   `yaml:",inline"`
+	nodeSelection `yaml:",inline"`
 }
 
-func newUpdateNodesTask(client *kubernetes.Clientset, cfg *config.Task) (*UpdateNodesTask, error) {
+func newUpdateNodesTask(client *kubernetes.Clientset, accessor NodeSelectionAccessor, rnd *rand.Rand, cfg *config.Task) (*UpdateNodesTask, error) {
 	if client == nil {
 		return nil, fmt.Errorf("kubernetes clientset not set")
 	}
@@ -55,7 +59,9 @@ func newUpdateNodesTask(client *kubernetes.Clientset, cfg *config.Task) (*Update
 			taskType: cfg.Type,
 			taskID:   cfg.ID,
 		},
-		client: client,
+		client:   client,
+		accessor: accessor,
+		rnd:      rnd,
 	}
 
 	if err := task.validate(task.taskType, task.taskID, cfg.Params); err != nil {
@@ -74,10 +80,18 @@ func (p *nodeStateParams) validate(taskType, taskID string, params map[string]in
 		return fmt.Errorf("failed to parse parameters in %s task %s: %v", taskType, taskID, err)
 	}
 
-	if len(p.Selectors) == 0 {
+	if p.isEmpty() {
 		return fmt.Errorf("missing node selectors in %s task %s", taskType, taskID)
 	}
 
+	if err = p.nodeSelection.validate(); err != nil {
+		return fmt.Errorf("invalid node names in %s task %s: %v", taskType, taskID, err)
+	}
+
+	if err = p.validateCount(); err != nil {
+		return fmt.Errorf("%v in %s task %s", err, taskType, taskID)
+	}
+
 	if len(p.State) == 0 {
 		return fmt.Errorf("missing state parameters in %s task %s", taskType, taskID)
 	}
@@ -98,31 +112,27 @@ func (task *UpdateNodesTask) Exec(ctx context.Context) error {
 		return fmt.Errorf("%s: failed to generate patch: %v", task.ID(), err)
 	}
 
-	for _, node := range nodeList.Items {
-		for _, selector := range task.Selectors {
-			if isMapSubset(node.Labels, selector) {
-				if patch.Root != nil {
-					if _, err := nodeClient.Patch(ctx, node.Name, types.MergePatchType, patch.Root, metav1.PatchOptions{}); err != nil {
-						return err
-					}
-				}
-				if patch.Status != nil {
-					if _, err := nodeClient.PatchStatus(ctx, node.Name, patch.Status); err != nil {
-						return err
-					}
-				}
+	refNodes, err := task.getRefNodes(task.accessor)
+	if err != nil {
+		return fmt.Errorf("%s: %v", task.ID(), err)
+	}
+
+	nodes := task.selectNodes(nodeList.Items, refNodes, task.rnd)
+	for _, node := range nodes {
+		if patch.Root != nil {
+			if _, err := nodeClient.Patch(ctx, node.Name, types.MergePatchType, patch.Root, metav1.PatchOptions{}); err != nil {
+				return err
+			}
+		}
+		if patch.Status != nil {
+			if _, err := nodeClient.PatchStatus(ctx, node.Name, patch.Status); err != nil {
+				return err
 			}
 		}
 	}
 
-	return nil
-}
+	names := nodeNames(nodes)
+	log.Infof("Updated %d nodes: %v", len(names), names)
 
-func isMapSubset(mapSet, mapSubset map[string]string) bool {
-	for key, value := range mapSubset {
-		if v, ok := mapSet[key]; !ok || v != value {
-			return false
-		}
-	}
-	return true
+	return task.accessor.SetSelectedNodes(task.taskID, names)
 }
@@ -30,6 +30,7 @@ func TestUpdateNodesTask(t *testing.T) {
 		name       string
 		params     map[string]interface{}
 		simClients bool
+		nodeNames  string
 		err        string
 		task       *UpdateNodesTask
 	}{
@@ -79,7 +80,110 @@ func TestUpdateNodesTask(t *testing.T) {
 							"spec": map[string]interface{}{"unschedulable": true},
 						},
 					},
-					Selectors: []map[string]string{{"key1": "val1"}, {"key2": "val2", "key3": "val3"}},
+					nodeSelection: nodeSelection{
+						Selectors: []map[string]string{{"key1": "val1"}, {"key2": "val2", "key3": "val3"}},
+					},
+				},
+				client: testK8sClient,
+			},
+		},
+		{
+			name: "Case 6a: invalid node names",
+			params: map[string]interface{}{
+				"names": map[string]interface{}{
+					"range": map[string]interface{}{"pattern": "node-{{._INDEX_}}"},
+				},
+				"state": map[string]interface{}{
+					"spec": map[string]interface{}{"unschedulable": true},
+				},
+			},
+			simClients: true,
+			err:        "invalid node names in UpdateNodes task update: missing ranges in name range",
+		},
+		{
+			name: "Case 6b: invalid node count",
+			params: map[string]interface{}{
+				"selectors": []map[string]string{{"key1": "val1"}},
+				"count":     "ten",
+				"state": map[string]interface{}{
+					"spec": map[string]interface{}{"unschedulable": true},
+				},
+			},
+			simClients: true,
+			err:        "invalid node count \"ten\" in UpdateNodes task update",
+		},
+		{
+			name: "Case 6c: unreferenced nodes",
+			params: map[string]interface{}{
+				"nodesFrom": "busy",
+				"state": map[string]interface{}{
+					"spec": map[string]interface{}{"unschedulable": true},
+				},
+			},
+			simClients: true,
+			err:        "UpdateNodes/update: unreferenced task ID busy",
+		},
+		{
+			name: "Case 7: valid input with node names",
+			params: map[string]interface{}{
+				"names": map[string]interface{}{
+					"range": map[string]interface{}{"pattern": "node-{{._INDEX_}}", "ranges": []string{"1-3", "5"}},
+				},
+				"state": map[string]interface{}{
+					"spec": map[string]interface{}{"unschedulable": true},
+				},
+			},
+			simClients: true,
+			nodeNames: `
+range:
+  pattern: "node-{{._INDEX_}}"
+  ranges: ["1-3", "5"]
+`,
+			task: &UpdateNodesTask{
+				BaseTask: BaseTask{
+					taskType: TaskUpdateNodes,
+					taskID:   taskID,
+				},
+				nodeStateParams: nodeStateParams{
+					StateParams: StateParams{
+						State: map[string]interface{}{
+							"spec": map[string]interface{}{"unschedulable": true},
+						},
+					},
+				},
+				client: testK8sClient,
+			},
+		},
+		{
+			name: "Case 8: valid input with random selection",
+			params: map[string]interface{}{
+				"selectors": []map[string]string{{"type": "kwok"}},
+				"count":     "10%",
+				"random":    true,
+				"spreadBy":  "rack",
+				"state": map[string]interface{}{
+					"spec": map[string]interface{}{"unschedulable": true},
+				},
+			},
+			simClients: true,
+			task: &UpdateNodesTask{
+				BaseTask: BaseTask{
+					taskType: TaskUpdateNodes,
+					taskID:   taskID,
+				},
+				nodeStateParams: nodeStateParams{
+					StateParams: StateParams{
+						State: map[string]interface{}{
+							"spec": map[string]interface{}{"unschedulable": true},
+						},
+					},
+					nodeSelection: nodeSelection{
+						Selectors: []map[string]string{{"type": "kwok"}},
+						Count:     "10%",
+						Random:    true,
+						SpreadBy:  "rack",
+						percent:   10,
+					},
 				},
 				client: testK8sClient,
 			},
@@ -100,6 +204,13 @@ func TestUpdateNodesTask(t *testing.T) {
 				require.Nil(t, tc.task)
 			} else {
 				require.NoError(t, err)
+				tc.task.accessor = eng
+				if len(tc.nodeNames) != 0 {
+					tc.task.Names = newTestNameSelector(t, tc.nodeNames)
+				}
+				// the random number generator is derived from the workflow seed
+				require.NotNil(t, task.(*UpdateNodesTask).rnd)
+				tc.task.rnd = task.(*UpdateNodesTask).rnd
 				require.Equal(t, tc.task, task)
 			}
 		})
@@ -53,12 +53,24 @@ func newUpdateObjTask(client *dynamic.DynamicClient, accessor ObjInfoAccessor, c
 		return nil, err
 	}
 
+	if len(task.State) == 0 {
+		return nil, fmt.Errorf("%s: missing parameter 'state'", task.ID())
+	}
+
+	if len(task.Assert) != 0 {
+		return nil, fmt.Errorf("%s: parameter 'assert' is not supported", task.ID())
+	}
+
+	if task.Quantifier != nil {
+		return nil, fmt.Errorf("%s: parameter 'quantifier' is not supported", task.ID())
+	}
+
 	return task, nil
 }
 
 // Exec implements Runnable interface
 func (task *UpdateObjTask) Exec(ctx context.Context) error {
-	info, err := task.accessor.GetObjInfo(task.RefTaskID)
+	info, err := task.getObjInfo(ctx)
 	if err != nil {
 		return err
 	}
@@ -69,15 +81,16 @@ func (task *UpdateObjTask) Exec(ctx context.Context) error {
 	}
 
 	gvr := info.GVR[task.Index]
-	for _, name := range info.Names {
+	for i, name := range info.Names {
+		ns := info.ObjNamespace(task.Index, i)
 		if patch.Root != nil {
-			_, err = task.client.Resource(gvr).Namespace(info.Namespace).Patch(ctx, name, types.MergePatchType, patch.Root, metav1.PatchOptions{})
+			_, err = task.client.Resource(gvr).Namespace(ns).Patch(ctx, name, types.MergePatchType, patch.Root, metav1.PatchOptions{})
 			if err != nil {
 				return fmt.Errorf("%s: failed to patch %s %s: %v", task.ID(), gvr.Resource, name, err)
 			}
 		}
 		if patch.Status != nil {
-			_, err = task.client.Resource(gvr).Namespace(info.Namespace).Patch(ctx, name, types.MergePatchType, patch.Root, metav1.PatchOptions{}, "status")
+			_, err = task.client.Resource(gvr).Namespace(ns).Patch(ctx, name, types.MergePatchType, patch.Root, metav1.PatchOptions{}, "status")
 			if err != nil {
 				return fmt.Errorf("%s: failed to patch status %s %s: %v", task.ID(), gvr.Resource, name, err)
 			}
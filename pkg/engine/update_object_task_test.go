@@ -59,7 +59,19 @@ func TestNewUpdateObjTask(t *testing.T) {
 			err:        "UpdateObj/update: unreferenced task ID 1",
 		},
 		{
-			name: "Case 4: valid input",
+			name: "Case 4a: unsupported assertions",
+			params: map[string]interface{}{
+				"refTaskId": 1,
+				"state":     map[string]interface{}{"a": "b"},
+				"assert": []interface{}{
+					map[string]interface{}{"path": ".status.active", "op": "exists"},
+				},
+			},
+			simClients: true,
+			err:        "UpdateObj/update: parameter 'assert' is not supported",
+		},
+		{
+			name: "Case 4b: valid input",
 			params: map[string]interface{}{
 				"refTaskId": 1,
 				"state":     map[string]interface{}{"a": "b"},
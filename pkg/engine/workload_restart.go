@@ -0,0 +1,251 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+	log "k8s.io/klog/v2"
+)
+
+// workload controllers supported by the restarts
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindDaemonSet   = "DaemonSet"
+)
+
+// workloadClient provides the operations of the workload controllers of one kind in one namespace
+type workloadClient struct {
+	list  func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error)
+	watch func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	patch func(ctx context.Context, name string, data []byte) error
+	// names returns the names of the listed workloads
+	names func(list runtime.Object) []string
+	// objType is the type of the watched objects
+	objType runtime.Object
+}
+
+// newWorkloadClient returns the client for the workload kind; default kind is Deployment
+func newWorkloadClient(client *kubernetes.Clientset, kind, namespace string) *workloadClient {
+	switch kind {
+	case KindStatefulSet:
+		c := client.AppsV1().StatefulSets(namespace)
+		return &workloadClient{
+			list:  func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) { return c.List(ctx, opts) },
+			watch: c.Watch,
+			patch: func(ctx context.Context, name string, data []byte) error {
+				_, err := c.Patch(ctx, name, k8stypes.StrategicMergePatchType, data, metav1.PatchOptions{})
+				return err
+			},
+			names: func(list runtime.Object) []string {
+				names := []string{}
+				for _, item := range list.(*appsv1.StatefulSetList).Items {
+					names = append(names, item.Name)
+				}
+				return names
+			},
+			objType: &appsv1.StatefulSet{},
+		}
+
+	case KindDaemonSet:
+		c := client.AppsV1().DaemonSets(namespace)
+		return &workloadClient{
+			list:  func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) { return c.List(ctx, opts) },
+			watch: c.Watch,
+			patch: func(ctx context.Context, name string, data []byte) error {
+				_, err := c.Patch(ctx, name, k8stypes.StrategicMergePatchType, data, metav1.PatchOptions{})
+				return err
+			},
+			names: func(list runtime.Object) []string {
+				names := []string{}
+				for _, item := range list.(*appsv1.DaemonSetList).Items {
+					names = append(names, item.Name)
+				}
+				return names
+			},
+			objType: &appsv1.DaemonSet{},
+		}
+
+	default:
+		c := client.AppsV1().Deployments(namespace)
+		return &workloadClient{
+			list:  func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) { return c.List(ctx, opts) },
+			watch: c.Watch,
+			patch: func(ctx context.Context, name string, data []byte) error {
+				_, err := c.Patch(ctx, name, k8stypes.StrategicMergePatchType, data, metav1.PatchOptions{})
+				return err
+			},
+			names: func(list runtime.Object) []string {
+				names := []string{}
+				for _, item := range list.(*appsv1.DeploymentList).Items {
+					names = append(names, item.Name)
+				}
+				return names
+			},
+			objType: &appsv1.Deployment{},
+		}
+	}
+}
+
+// restartWorkloads restarts the workload controllers selected by name or labels, and waits for their rollouts
+func restartWorkloads(ctx context.Context, k8sClient *kubernetes.Clientset, dr *deploymentRestart) error {
+	kind := dr.kind()
+	client := newWorkloadClient(k8sClient, kind, dr.Namespace)
+
+	names := []string{dr.Name}
+	if len(dr.Name) == 0 {
+		lbl := labels.SelectorFromSet(dr.Labels).String()
+
+		list, err := client.list(ctx, metav1.ListOptions{LabelSelector: lbl})
+		if err != nil {
+			log.InfoS("Warning: skipping restart", "kind", kind, "labels", lbl, "error", err.Error())
+			return nil
+		}
+
+		if names = client.names(list); len(names) == 0 {
+			log.InfoS("Warning: nothing to restart", "kind", kind, "labels", lbl)
+			return nil
+		}
+	}
+
+	update := fmt.Sprintf(`{"spec": {"template": {"metadata": {"annotations": {"kubectl.kubernetes.io/restartedAt": "%s"}}}}}`,
+		time.Now().Format("2006-01-02T15:04:05-07:00"))
+
+	for _, name := range names {
+		log.Infof("Restarting %s %s", kind, objectKey(dr.Namespace, name))
+		if err := client.patch(ctx, name, []byte(update)); err != nil {
+			return fmt.Errorf("failed to update %s %s: %s", kind, name, err.Error())
+		}
+	}
+
+	for _, name := range names {
+		if err := client.waitForRollout(ctx, name); err != nil {
+			return fmt.Errorf("failed to restart %s %s: %v", kind, name, err)
+		}
+		log.Infof("Restarted %s %s", kind, objectKey(dr.Namespace, name))
+	}
+
+	return nil
+}
+
+// waitForRollout watches the workload until its rollout completes, or until the context is done
+func (c *workloadClient) waitForRollout(ctx context.Context, name string) error {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			opts.FieldSelector = fieldSelector
+			return c.list(ctx, opts)
+		},
+		WatchFuncWithContext: func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+			opts.FieldSelector = fieldSelector
+			return c.watch(ctx, opts)
+		},
+	}
+
+	_, err := watchtools.UntilWithSync(ctx, lw, c.objType, nil, func(event watch.Event) (bool, error) {
+		switch event.Type {
+		case watch.Deleted:
+			return false, fmt.Errorf("object has been deleted")
+		case watch.Added, watch.Modified:
+			done, status := rolloutStatus(event.Object)
+			log.V(4).Infof("Rollout of %s: %s", name, status)
+			return done, nil
+		default:
+			return false, nil
+		}
+	})
+	return err
+}
+
+// rolloutStatus returns true if the rollout of the workload is complete, and the rollout status otherwise.
+// It follows "kubectl rollout status": the controller must have observed the latest generation,
+// and all replicas must be updated and available, or ready for the controllers without availability status.
+func rolloutStatus(obj runtime.Object) (bool, string) {
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		if w.Generation > w.Status.ObservedGeneration {
+			return false, "waiting for the rollout to be observed"
+		}
+		replicas := int32(1)
+		if w.Spec.Replicas != nil {
+			replicas = *w.Spec.Replicas
+		}
+		switch {
+		case w.Status.UpdatedReplicas < replicas:
+			return false, fmt.Sprintf("%d out of %d replicas updated", w.Status.UpdatedReplicas, replicas)
+		case w.Status.Replicas > w.Status.UpdatedReplicas:
+			return false, fmt.Sprintf("%d old replicas pending termination", w.Status.Replicas-w.Status.UpdatedReplicas)
+		case w.Status.AvailableReplicas < w.Status.UpdatedReplicas:
+			return false, fmt.Sprintf("%d out of %d updated replicas available", w.Status.AvailableReplicas, w.Status.UpdatedReplicas)
+		}
+		return true, "complete"
+
+	case *appsv1.StatefulSet:
+		if w.Generation > w.Status.ObservedGeneration {
+			return false, "waiting for the rollout to be observed"
+		}
+		replicas := int32(1)
+		if w.Spec.Replicas != nil {
+			replicas = *w.Spec.Replicas
+		}
+		if w.Status.ReadyReplicas < replicas {
+			return false, fmt.Sprintf("%d out of %d replicas ready", w.Status.ReadyReplicas, replicas)
+		}
+		if w.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+			return true, "complete"
+		}
+		// only the replicas with ordinals at or above the partition are updated
+		if ru := w.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil && *ru.Partition > 0 {
+			if expected := replicas - *ru.Partition; w.Status.UpdatedReplicas < expected {
+				return false, fmt.Sprintf("%d out of %d partitioned replicas updated", w.Status.UpdatedReplicas, expected)
+			}
+			return true, "complete"
+		}
+		if w.Status.UpdateRevision != w.Status.CurrentRevision {
+			return false, fmt.Sprintf("%d out of %d replicas updated", w.Status.UpdatedReplicas, replicas)
+		}
+		return true, "complete"
+
+	case *appsv1.DaemonSet:
+		if w.Generation > w.Status.ObservedGeneration {
+			return false, "waiting for the rollout to be observed"
+		}
+		switch {
+		case w.Status.UpdatedNumberScheduled < w.Status.DesiredNumberScheduled:
+			return false, fmt.Sprintf("%d out of %d pods updated", w.Status.UpdatedNumberScheduled, w.Status.DesiredNumberScheduled)
+		case w.Status.NumberReady < w.Status.DesiredNumberScheduled:
+			return false, fmt.Sprintf("%d out of %d pods ready", w.Status.NumberReady, w.Status.DesiredNumberScheduled)
+		}
+		return true, "complete"
+
+	default:
+		return false, fmt.Sprintf("unsupported object %T", obj)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"syscall"
 
 	"github.com/oklog/run"
@@ -95,7 +96,11 @@ func (h *WorkflowHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = engine.Run(r.Context(), h.eng, &workflow); err != nil {
+	// the workflows submitted to the server must not read its environment
+	err = engine.Run(engine.WithRemoteWorkflow(r.Context()), h.eng, &workflow)
+	w.Header().Set("X-Knavigator-Seed", strconv.FormatInt(*workflow.Seed, 10))
+	w.Header().Set("X-Knavigator-Run-Id", workflow.RunID)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,368 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	AssertOpExists    = "exists"
+	AssertOpNotExists = "notExists"
+	AssertOpEq        = "=="
+	AssertOpNe        = "!="
+	AssertOpGt        = ">"
+	AssertOpGe        = ">="
+	AssertOpLt        = "<"
+	AssertOpLe        = "<="
+	AssertOpIn        = "in"
+	AssertOpNotIn     = "notIn"
+	AssertOpRegex     = "regex"
+
+	AssertMatchAny   = "any"
+	AssertMatchAll   = "all"
+	AssertMatchNone  = "none"
+	AssertMatchExact = "exact"
+	AssertMatchCount = "count"
+)
+
+// Assertion represents a predicate on the values selected from an object by a JSONPath expression, where:
+//   - "Path" is a JSONPath expression, e.g. `.status.conditions[?(@.type=="Admitted")].status`
+//   - "Op" is one of the AssertOp* operators.
+//   - "Value" is the value to compare with; not used with "exists" and "notExists".
+//   - "Match" defines how the operator is applied to the selected values:
+//     "any" (default): at least one value satisfies the operator;
+//     "all": there is at least one value, and all values satisfy the operator;
+//     "none": no value satisfies the operator;
+//     "exact": the selected values are equal to the list in "Value";
+//     "count": the number of selected values is compared with "Value".
+//
+// If the path selects a single list, "exact" and "count" are applied to the list elements.
+type Assertion struct {
+	Path  string      `yaml:"path"`
+	Op    string      `yaml:"op,omitempty"`
+	Value interface{} `yaml:"value,omitempty"`
+	Match string      `yaml:"match,omitempty"`
+
+	// derived
+	mutex  sync.Mutex
+	jp     *jsonpath.JSONPath
+	regexp *regexp.Regexp
+}
+
+// Finalize validates and finalizes instantiation of Assertion
+func (a *Assertion) Finalize() error {
+	if len(a.Path) == 0 {
+		return fmt.Errorf("missing assertion path")
+	}
+
+	path := a.Path
+	if !strings.HasPrefix(path, "{") {
+		path = "{" + path + "}"
+	}
+	a.jp = jsonpath.New("assert").AllowMissingKeys(true)
+	if err := a.jp.Parse(path); err != nil {
+		return fmt.Errorf("failed to parse path %s: %v", a.Path, err)
+	}
+
+	switch a.Match {
+	case "":
+		a.Match = AssertMatchAny
+	case AssertMatchAny, AssertMatchAll, AssertMatchNone:
+		// nop
+	case AssertMatchExact:
+		if len(a.Op) == 0 {
+			a.Op = AssertOpEq
+		}
+		if a.Op != AssertOpEq {
+			return fmt.Errorf("path %s: match %q supports only operator %q", a.Path, a.Match, AssertOpEq)
+		}
+		if _, ok := a.Value.([]interface{}); !ok {
+			return fmt.Errorf("path %s: match %q requires a list value", a.Path, a.Match)
+		}
+		return nil
+	case AssertMatchCount:
+		switch a.Op {
+		case AssertOpEq, AssertOpNe, AssertOpGt, AssertOpGe, AssertOpLt, AssertOpLe:
+			// nop
+		default:
+			return fmt.Errorf("path %s: match %q does not support operator %q", a.Path, a.Match, a.Op)
+		}
+		if _, ok := toFloat(a.Value); !ok {
+			return fmt.Errorf("path %s: match %q requires a numeric value", a.Path, a.Match)
+		}
+		return nil
+	default:
+		return fmt.Errorf("path %s: invalid match %q; supported: %s, %s, %s, %s, %s", a.Path, a.Match,
+			AssertMatchAny, AssertMatchAll, AssertMatchNone, AssertMatchExact, AssertMatchCount)
+	}
+
+	switch a.Op {
+	case AssertOpExists, AssertOpNotExists:
+		// nop
+	case AssertOpEq, AssertOpNe, AssertOpGt, AssertOpGe, AssertOpLt, AssertOpLe:
+		if a.Value == nil {
+			return fmt.Errorf("path %s: missing value for operator %q", a.Path, a.Op)
+		}
+	case AssertOpIn, AssertOpNotIn:
+		if _, ok := a.Value.([]interface{}); !ok {
+			return fmt.Errorf("path %s: operator %q requires a list value", a.Path, a.Op)
+		}
+	case AssertOpRegex:
+		str, ok := a.Value.(string)
+		if !ok {
+			return fmt.Errorf("path %s: operator %q requires a string value", a.Path, a.Op)
+		}
+		var err error
+		if a.regexp, err = regexp.Compile(str); err != nil {
+			return fmt.Errorf("path %s: failed to compile regexp %q: %v", a.Path, str, err)
+		}
+	default:
+		return fmt.Errorf("path %s: invalid operator %q", a.Path, a.Op)
+	}
+
+	return nil
+}
+
+// Check evaluates the assertion against the object, and returns an error describing the mismatch, if any
+func (a *Assertion) Check(obj map[string]interface{}) error {
+	values, err := a.find(obj)
+	if err != nil {
+		return err
+	}
+
+	var ok bool
+	switch a.Op {
+	case AssertOpExists:
+		ok = len(values) != 0
+	case AssertOpNotExists:
+		ok = len(values) == 0
+	default:
+		switch a.Match {
+		case AssertMatchExact:
+			ok = equalLists(expandList(values), a.Value.([]interface{}))
+		case AssertMatchCount:
+			ok, err = compare(len(expandList(values)), a.Op, a.Value, nil)
+		default:
+			ok, err = a.match(values)
+		}
+		if err != nil {
+			return fmt.Errorf("path %s: %v", a.Path, err)
+		}
+	}
+
+	if !ok {
+		return fmt.Errorf("assertion failed: path %s, match %s, op %s, value %v; actual %v", a.Path, a.Match, a.Op, a.Value, values)
+	}
+	return nil
+}
+
+// find returns the values selected by the path
+func (a *Assertion) find(obj map[string]interface{}) ([]interface{}, error) {
+	a.mutex.Lock()
+	results, err := a.jp.FindResults(obj)
+	a.mutex.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate path %s: %v", a.Path, err)
+	}
+
+	values := []interface{}{}
+	for _, res := range results {
+		for _, v := range res {
+			if v.IsValid() && v.CanInterface() {
+				values = append(values, v.Interface())
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// match applies the operator to the values according to the "any", "all" or "none" matching mode
+func (a *Assertion) match(values []interface{}) (bool, error) {
+	count := 0
+	for _, val := range values {
+		ok, err := compare(val, a.Op, a.Value, a.regexp)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			count++
+		}
+	}
+
+	switch a.Match {
+	case AssertMatchAll:
+		return len(values) != 0 && count == len(values), nil
+	case AssertMatchNone:
+		return count == 0, nil
+	default:
+		return count != 0, nil
+	}
+}
+
+// compare applies the operator to the actual and expected values
+func compare(actual interface{}, op string, expected interface{}, re *regexp.Regexp) (bool, error) {
+	switch op {
+	case AssertOpEq:
+		return equalValues(actual, expected), nil
+	case AssertOpNe:
+		return !equalValues(actual, expected), nil
+	case AssertOpIn, AssertOpNotIn:
+		found := false
+		for _, val := range expected.([]interface{}) {
+			if equalValues(actual, val) {
+				found = true
+				break
+			}
+		}
+		return found == (op == AssertOpIn), nil
+	case AssertOpRegex:
+		switch actual.(type) {
+		case map[string]interface{}, []interface{}, nil:
+			return false, nil
+		}
+		return re.MatchString(fmt.Sprintf("%v", actual)), nil
+	}
+
+	res, err := orderValues(actual, expected)
+	if err != nil {
+		return false, err
+	}
+
+	switch op {
+	case AssertOpGt:
+		return res > 0, nil
+	case AssertOpGe:
+		return res >= 0, nil
+	case AssertOpLt:
+		return res < 0, nil
+	case AssertOpLe:
+		return res <= 0, nil
+	}
+
+	return false, fmt.Errorf("invalid operator %q", op)
+}
+
+// equalValues compares values, treating all numeric types as equal if they have the same value
+func equalValues(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+
+	switch x := a.(type) {
+	case []interface{}:
+		y, ok := b.([]interface{})
+		return ok && equalLists(x, y)
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, val := range x {
+			if v, ok := y[key]; !ok || !equalValues(val, v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func equalLists(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalValues(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// orderValues compares numbers, resource quantities or strings, and returns -1, 0, or 1
+func orderValues(a, b interface{}) (int, error) {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			default:
+				return 0, nil
+			}
+		}
+	}
+
+	x, okA := a.(string)
+	y, okB := b.(string)
+	if !okA || !okB {
+		return 0, fmt.Errorf("cannot compare %v (%T) and %v (%T)", a, a, b, b)
+	}
+
+	qa, errA := resource.ParseQuantity(x)
+	qb, errB := resource.ParseQuantity(y)
+	if errA == nil && errB == nil {
+		return qa.Cmp(qb), nil
+	}
+
+	return strings.Compare(x, y), nil
+}
+
+// expandList returns the list elements if the only selected value is a list
+func expandList(values []interface{}) []interface{} {
+	if len(values) == 1 {
+		if list, ok := values[0].([]interface{}); ok {
+			return list
+		}
+	}
+	return values
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
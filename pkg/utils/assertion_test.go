@@ -0,0 +1,229 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAssertionFinalize(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "Case 1: missing path",
+			input: `op: exists`,
+			err:   "missing assertion path",
+		},
+		{
+			name:  "Case 2: invalid path",
+			input: `{path: ".status[", op: exists}`,
+			err:   "failed to parse path .status[: unterminated array",
+		},
+		{
+			name:  "Case 3: invalid operator",
+			input: `{path: .status.phase, op: "=~", value: Running}`,
+			err:   `path .status.phase: invalid operator "=~"`,
+		},
+		{
+			name:  "Case 4: invalid match",
+			input: `{path: .status.phase, op: "==", value: Running, match: some}`,
+			err:   `path .status.phase: invalid match "some"; supported: any, all, none, exact, count`,
+		},
+		{
+			name:  "Case 5: missing value",
+			input: `{path: .status.active, op: ">="}`,
+			err:   `path .status.active: missing value for operator ">="`,
+		},
+		{
+			name:  "Case 6: invalid regexp",
+			input: `{path: .status.phase, op: regex, value: "(Run"}`,
+			err:   "path .status.phase: failed to compile regexp \"(Run\": error parsing regexp: missing closing ): `(Run`",
+		},
+		{
+			name:  "Case 7: non-list value for in",
+			input: `{path: .status.phase, op: in, value: Running}`,
+			err:   `path .status.phase: operator "in" requires a list value`,
+		},
+		{
+			name:  "Case 8: non-numeric count",
+			input: `{path: .status.conditions, op: ">=", value: two, match: count}`,
+			err:   `path .status.conditions: match "count" requires a numeric value`,
+		},
+		{
+			name:  "Case 9: non-list exact value",
+			input: `{path: ".spec.containers[*].name", value: test, match: exact}`,
+			err:   `path .spec.containers[*].name: match "exact" requires a list value`,
+		},
+		{
+			name:  "Case 10: valid input",
+			input: `{path: "{.status.conditions[?(@.type==\"Admitted\")].status}", op: "==", value: "True"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var a Assertion
+			require.NoError(t, yaml.Unmarshal([]byte(tc.input), &a))
+			err := a.Finalize()
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAssertionCheck(t *testing.T) {
+	obj := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name": "job1",
+		},
+		"spec": map[string]interface{}{
+			"parallelism": int64(4),
+			"containers": []interface{}{
+				map[string]interface{}{"name": "main", "image": "nvcr.io/nvidia/pytorch"},
+				map[string]interface{}{"name": "sidecar", "image": "nvcr.io/nvidia/dcgm"},
+			},
+		},
+		"status": map[string]interface{}{
+			"active": int64(3),
+			"phase":  "Running",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "QuotaReserved", "status": "True"},
+				map[string]interface{}{"type": "Admitted", "status": "True"},
+				map[string]interface{}{"type": "Evicted", "status": "False"},
+			},
+			"resources": map[string]interface{}{"memory": "2Gi"},
+			"startTime": "2024-05-01T10:00:00Z",
+		},
+	}
+
+	testCases := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "Case 1a: exists",
+			input: `{path: .status.phase, op: exists}`,
+		},
+		{
+			name:  "Case 1b: exists failed",
+			input: `{path: .status.missing, op: exists}`,
+			err:   "assertion failed: path .status.missing, match any, op exists, value <nil>; actual []",
+		},
+		{
+			name:  "Case 1c: not exists",
+			input: `{path: ".status.conditions[?(@.type==\"Failed\")]", op: notExists}`,
+		},
+		{
+			name:  "Case 2a: condition lookup by type",
+			input: `{path: ".status.conditions[?(@.type==\"Admitted\")].status", op: "==", value: "True"}`,
+		},
+		{
+			name:  "Case 2b: condition lookup by type failed",
+			input: `{path: ".status.conditions[?(@.type==\"Evicted\")].status", op: "==", value: "True"}`,
+			err:   "assertion failed: path .status.conditions[?(@.type==\"Evicted\")].status, match any, op ==, value True; actual [False]",
+		},
+		{
+			name:  "Case 3a: numeric comparison",
+			input: `{path: .status.active, op: ">=", value: 2}`,
+		},
+		{
+			name:  "Case 3b: numeric comparison failed",
+			input: `{path: .status.active, op: ">", value: 3}`,
+			err:   "assertion failed: path .status.active, match any, op >, value 3; actual [3]",
+		},
+		{
+			name:  "Case 3c: quantity comparison",
+			input: `{path: .status.resources.memory, op: ">", value: 1500Mi}`,
+		},
+		{
+			name:  "Case 3d: timestamp comparison",
+			input: `{path: .status.startTime, op: "<", value: "2024-06-01T00:00:00Z"}`,
+		},
+		{
+			name:  "Case 3e: incomparable types",
+			input: `{path: .status.phase, op: "<", value: 5}`,
+			err:   "path .status.phase: cannot compare Running (string) and 5 (int)",
+		},
+		{
+			name:  "Case 4a: in",
+			input: `{path: .status.phase, op: in, value: [Pending, Running]}`,
+		},
+		{
+			name:  "Case 4b: not in",
+			input: `{path: .status.phase, op: notIn, value: [Failed, Succeeded]}`,
+		},
+		{
+			name:  "Case 5a: regex with all",
+			input: `{path: ".spec.containers[*].image", op: regex, value: "^nvcr.io/", match: all}`,
+		},
+		{
+			name:  "Case 5b: regex with none",
+			input: `{path: ".spec.containers[*].image", op: regex, value: "^docker.io/", match: none}`,
+		},
+		{
+			name:  "Case 5c: all without values",
+			input: `{path: ".spec.volumes[*].name", op: "==", value: data, match: all}`,
+			err:   "assertion failed: path .spec.volumes[*].name, match all, op ==, value data; actual []",
+		},
+		{
+			name:  "Case 6a: exact",
+			input: `{path: ".spec.containers[*].name", value: [main, sidecar], match: exact}`,
+		},
+		{
+			name:  "Case 6b: exact failed",
+			input: `{path: ".spec.containers[*].name", value: [main], match: exact}`,
+			err:   "assertion failed: path .spec.containers[*].name, match exact, op ==, value [main]; actual [main sidecar]",
+		},
+		{
+			name:  "Case 7a: count of list elements",
+			input: `{path: .status.conditions, op: "==", value: 3, match: count}`,
+		},
+		{
+			name:  "Case 7b: count of filtered elements",
+			input: `{path: ".status.conditions[?(@.status==\"True\")]", op: ">=", value: 2, match: count}`,
+		},
+		{
+			name:  "Case 7c: count failed",
+			input: `{path: ".spec.containers[*]", op: "<", value: 2, match: count}`,
+			err:   "assertion failed: path .spec.containers[*], match count, op <, value 2; actual [map[image:nvcr.io/nvidia/pytorch name:main] map[image:nvcr.io/nvidia/dcgm name:sidecar]]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var a Assertion
+			require.NoError(t, yaml.Unmarshal([]byte(tc.input), &a))
+			require.NoError(t, a.Finalize())
+			err := a.Check(obj)
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,417 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Template missing key modes; see "missingkey" option of text/template
+const (
+	MissingKeyDefault = "default"
+	MissingKeyZero    = "zero"
+	MissingKeyError   = "error"
+)
+
+// TemplateFuncs returns the functions available in object templates.
+// The functions follow the semantics of their Sprig counterparts.
+// Random functions draw from rnd, so that the templates render the same output with the same workflow seed.
+func TemplateFuncs(rnd *rand.Rand) template.FuncMap {
+	r := &lockedRand{rnd: rnd}
+
+	return template.FuncMap{
+		// defaults and conditions
+		"default":  defaultValue,
+		"empty":    isEmpty,
+		"coalesce": coalesce,
+		"ternary":  ternary,
+		// arithmetic
+		"add": add,
+		"sub": sub,
+		"mul": mul,
+		"div": div,
+		"mod": mod,
+		"max": maxInt,
+		"min": minInt,
+		// strings
+		"quote":   quote,
+		"squote":  squote,
+		"upper":   strings.ToUpper,
+		"lower":   strings.ToLower,
+		"trim":    strings.TrimSpace,
+		"replace": replace,
+		"join":    join,
+		// serialization
+		"toYaml":  toYaml,
+		"toJson":  toJSON,
+		"indent":  indent,
+		"nindent": nindent,
+		// lists
+		"until": until,
+		// random values
+		"randInt":      r.randInt,
+		"randAlphaNum": r.randAlphaNum,
+		// environment
+		"env": os.Getenv,
+	}
+}
+
+// MaxTemplateSize limits the size of the lists and strings allocated by the template functions,
+// so that a template cannot exhaust the memory of the server
+const MaxTemplateSize = 1 << 20
+
+// checkSize validates the size of the list or string allocated by the template function
+func checkSize(fn string, n int64) error {
+	if n < 0 {
+		return fmt.Errorf("%s: invalid size %d", fn, n)
+	}
+	if n > MaxTemplateSize {
+		return fmt.Errorf("%s: size %d exceeds the limit %d", fn, n, MaxTemplateSize)
+	}
+	return nil
+}
+
+// DisableEnv replaces the "env" function with the one failing the template execution,
+// so that the templates submitted remotely cannot read the environment variables of the server
+func DisableEnv(funcs template.FuncMap) template.FuncMap {
+	funcs["env"] = func(string) (string, error) {
+		return "", fmt.Errorf("env is disabled for remote workflows")
+	}
+	return funcs
+}
+
+// ParseTemplate parses the template with the template functions and the missing key mode
+func ParseTemplate(name, text string, funcs template.FuncMap, missingKey string) (*template.Template, error) {
+	tpl := template.New(name).Funcs(funcs)
+	if len(missingKey) != 0 {
+		tpl = tpl.Option("missingkey=" + missingKey)
+	}
+	return tpl.Parse(text)
+}
+
+// ValidateMissingKey validates the missing key mode
+func ValidateMissingKey(missingKey string) error {
+	switch missingKey {
+	case "", MissingKeyDefault, MissingKeyZero, MissingKeyError:
+		return nil
+	default:
+		return fmt.Errorf("invalid missingKey %q; supported: %s, %s, %s",
+			missingKey, MissingKeyDefault, MissingKeyZero, MissingKeyError)
+	}
+}
+
+// lockedRand makes the random number generator safe for concurrent template execution
+type lockedRand struct {
+	mutex sync.Mutex
+	rnd   *rand.Rand
+}
+
+// randInt returns a random integer in [lower, upper)
+func (r *lockedRand) randInt(lower, upper interface{}) (int64, error) {
+	lo, err := castInt64(lower)
+	if err != nil {
+		return 0, err
+	}
+	hi, err := castInt64(upper)
+	if err != nil {
+		return 0, err
+	}
+	if hi <= lo {
+		return 0, fmt.Errorf("randInt: invalid range [%d, %d)", lo, hi)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return lo + r.rnd.Int63n(hi-lo), nil
+}
+
+// randAlphaNum returns a random alphanumeric string of the given length
+func (r *lockedRand) randAlphaNum(length interface{}) (string, error) {
+	const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	n, err := castInt64(length)
+	if err != nil {
+		return "", err
+	}
+	if err = checkSize("randAlphaNum", n); err != nil {
+		return "", err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = chars[r.rnd.Intn(len(chars))]
+	}
+	return string(b), nil
+}
+
+// defaultValue returns the given value, or the default value if the given value is empty
+func defaultValue(def interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || isEmpty(given[0]) {
+		return def
+	}
+	return given[0]
+}
+
+// isEmpty returns true if the value is nil, or the zero value of its type, or an empty collection
+func isEmpty(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+// coalesce returns the first non-empty value
+func coalesce(vals ...interface{}) interface{} {
+	for _, val := range vals {
+		if !isEmpty(val) {
+			return val
+		}
+	}
+	return nil
+}
+
+// ternary returns vtrue if the condition is true, and vfalse otherwise
+func ternary(vtrue, vfalse interface{}, cond bool) interface{} {
+	if cond {
+		return vtrue
+	}
+	return vfalse
+}
+
+func add(vals ...interface{}) (int64, error) {
+	var sum int64
+	for _, val := range vals {
+		n, err := castInt64(val)
+		if err != nil {
+			return 0, err
+		}
+		sum += n
+	}
+	return sum, nil
+}
+
+func mul(vals ...interface{}) (int64, error) {
+	var product int64 = 1
+	for _, val := range vals {
+		n, err := castInt64(val)
+		if err != nil {
+			return 0, err
+		}
+		product *= n
+	}
+	return product, nil
+}
+
+func sub(a, b interface{}) (int64, error) {
+	x, y, err := castInt64Pair(a, b)
+	if err != nil {
+		return 0, err
+	}
+	return x - y, nil
+}
+
+func div(a, b interface{}) (int64, error) {
+	x, y, err := castInt64Pair(a, b)
+	if err != nil {
+		return 0, err
+	}
+	if y == 0 {
+		return 0, fmt.Errorf("div: division by zero")
+	}
+	return x / y, nil
+}
+
+func mod(a, b interface{}) (int64, error) {
+	x, y, err := castInt64Pair(a, b)
+	if err != nil {
+		return 0, err
+	}
+	if y == 0 {
+		return 0, fmt.Errorf("mod: division by zero")
+	}
+	return x % y, nil
+}
+
+func maxInt(first interface{}, rest ...interface{}) (int64, error) {
+	return reduceInt64(first, rest, func(a, b int64) bool { return b > a })
+}
+
+func minInt(first interface{}, rest ...interface{}) (int64, error) {
+	return reduceInt64(first, rest, func(a, b int64) bool { return b < a })
+}
+
+// reduceInt64 returns the value preferred by the comparison function
+func reduceInt64(first interface{}, rest []interface{}, better func(a, b int64) bool) (int64, error) {
+	res, err := castInt64(first)
+	if err != nil {
+		return 0, err
+	}
+	for _, val := range rest {
+		n, err := castInt64(val)
+		if err != nil {
+			return 0, err
+		}
+		if better(res, n) {
+			res = n
+		}
+	}
+	return res, nil
+}
+
+func quote(vals ...interface{}) string {
+	out := make([]string, 0, len(vals))
+	for _, val := range vals {
+		if val != nil {
+			out = append(out, strconv.Quote(fmt.Sprint(val)))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func squote(vals ...interface{}) string {
+	out := make([]string, 0, len(vals))
+	for _, val := range vals {
+		if val != nil {
+			out = append(out, "'"+fmt.Sprint(val)+"'")
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func replace(oldStr, newStr, src string) string {
+	return strings.ReplaceAll(src, oldStr, newStr)
+}
+
+func join(sep string, list interface{}) string {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Array && v.Kind() != reflect.Slice {
+		return fmt.Sprint(list)
+	}
+
+	out := make([]string, v.Len())
+	for i := range out {
+		out[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(out, sep)
+}
+
+func toYaml(val interface{}) (string, error) {
+	data, err := yaml.Marshal(val)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+func toJSON(val interface{}) (string, error) {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func indent(spaces int, s string) (string, error) {
+	if err := checkSize("indent", int64(spaces)); err != nil {
+		return "", err
+	}
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad), nil
+}
+
+func nindent(spaces int, s string) (string, error) {
+	out, err := indent(spaces, s)
+	if err != nil {
+		return "", err
+	}
+	return "\n" + out, nil
+}
+
+func until(count interface{}) ([]int, error) {
+	n, err := castInt64(count)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSize("until", n); err != nil {
+		return nil, err
+	}
+	list := make([]int, 0, n)
+	for i := 0; i < int(n); i++ {
+		list = append(list, i)
+	}
+	return list, nil
+}
+
+func castInt64Pair(a, b interface{}) (int64, int64, error) {
+	x, err := castInt64(a)
+	if err != nil {
+		return 0, 0, err
+	}
+	y, err := castInt64(b)
+	if err != nil {
+		return 0, 0, err
+	}
+	return x, y, nil
+}
+
+// castInt64 converts template values, which are often parsed from YAML, to int64
+func castInt64(val interface{}) (int64, error) {
+	if val == nil {
+		return 0, nil
+	}
+
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), nil // #nosec G115 // template values are small
+	case reflect.Float32, reflect.Float64:
+		return int64(v.Float()), nil
+	case reflect.String:
+		n, err := strconv.ParseInt(strings.TrimSpace(v.String()), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to convert %q to integer", v.String())
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("failed to convert %v (%T) to integer", val, val)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	t.Setenv("KNAVIGATOR_TEST_ENV", "value")
+
+	params := map[string]interface{}{
+		"replicas": 3,
+		"cpu":      "250",
+		"empty":    "",
+		"labels":   map[string]interface{}{"app": "test"},
+		"list":     []interface{}{"a", "b"},
+	}
+
+	testCases := []struct {
+		name       string
+		text       string
+		missingKey string
+		out        string
+		err        string
+	}{
+		{
+			name: "Case 1: default",
+			text: `{{ .empty | default "none" }} {{ .replicas | default 1 }} {{ .undefined | default 2 }}`,
+			out:  "none 3 2",
+		},
+		{
+			name: "Case 2: arithmetic",
+			text: `{{ add .replicas 1 }} {{ sub .cpu 50 }} {{ mul .replicas .cpu }} {{ div 7 2 }} {{ mod 7 2 }} {{ max 1 .replicas 2 }} {{ min 4 .replicas }}`,
+			out:  "4 200 750 3 1 3 3",
+		},
+		{
+			name: "Case 3: division by zero",
+			text: `{{ div .replicas 0 }}`,
+			err:  "template: test:1:3: executing \"test\" at <div .replicas 0>: error calling div: div: division by zero",
+		},
+		{
+			name: "Case 4: strings",
+			text: `{{ quote .cpu }} {{ squote "x" }} {{ upper "a" }} {{ trim " b " }} {{ replace "-" "_" "a-b" }} {{ join "," .list }}`,
+			out:  `"250" 'x' A b a_b a,b`,
+		},
+		{
+			name: "Case 5: serialization",
+			text: `labels:{{ toYaml .labels | nindent 2 }} {{ toJson .list }}`,
+			out:  "labels:\n  app: test [\"a\",\"b\"]",
+		},
+		{
+			name: "Case 6: conditions and lists",
+			text: `{{ ternary "yes" "no" (empty .empty) }} {{ coalesce .empty .cpu }} {{ range until 3 }}{{ . }}{{ end }}`,
+			out:  "yes 250 012",
+		},
+		{
+			name: "Case 7: environment",
+			text: `{{ env "KNAVIGATOR_TEST_ENV" }}`,
+			out:  "value",
+		},
+		{
+			name: "Case 8: missing key with default mode",
+			text: `{{ .undefined }}`,
+			out:  "<no value>",
+		},
+		{
+			name:       "Case 9: missing key with error mode",
+			text:       `{{ .undefined }}`,
+			missingKey: MissingKeyError,
+			err:        "template: test:1:3: executing \"test\" at <.undefined>: map has no entry for key \"undefined\"",
+		},
+		{
+			name: "Case 10: negative list size",
+			text: `{{ range until -1 }}{{ . }}{{ end }}`,
+			err:  "template: test:1:9: executing \"test\" at <until -1>: error calling until: until: invalid size -1",
+		},
+		{
+			name: "Case 11: list size over the limit",
+			text: `{{ range until 100000000000 }}{{ . }}{{ end }}`,
+			err:  "template: test:1:9: executing \"test\" at <until 100000000000>: error calling until: until: size 100000000000 exceeds the limit 1048576",
+		},
+		{
+			name: "Case 12: string size over the limit",
+			text: `{{ randAlphaNum 100000000000 }}`,
+			err:  "template: test:1:3: executing \"test\" at <randAlphaNum 100000000000>: error calling randAlphaNum: randAlphaNum: size 100000000000 exceeds the limit 1048576",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := ParseTemplate("test", tc.text, TemplateFuncs(rand.New(rand.NewSource(1))), tc.missingKey)
+			require.NoError(t, err)
+
+			buf := new(bytes.Buffer)
+			err = tpl.Execute(buf, params)
+			if len(tc.err) != 0 {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.out, buf.String())
+			}
+		})
+	}
+}
+
+func TestTemplateRandFuncs(t *testing.T) {
+	render := func(seed int64) string {
+		tpl, err := ParseTemplate("test", `{{ randInt 0 100 }}-{{ randAlphaNum 8 }}`, TemplateFuncs(rand.New(rand.NewSource(seed))), "")
+		require.NoError(t, err)
+
+		buf := new(bytes.Buffer)
+		require.NoError(t, tpl.Execute(buf, nil))
+		return buf.String()
+	}
+
+	// the same seed renders the same output
+	out := render(42)
+	require.Regexp(t, "^[0-9]{1,2}-[a-zA-Z0-9]{8}$", out)
+	require.Equal(t, out, render(42))
+	require.NotEqual(t, out, render(43))
+}
+
+func TestValidateMissingKey(t *testing.T) {
+	for _, key := range []string{"", MissingKeyDefault, MissingKeyZero, MissingKeyError} {
+		require.NoError(t, ValidateMissingKey(key))
+	}
+	require.EqualError(t, ValidateMissingKey("invalid"), `invalid missingKey "invalid"; supported: default, zero, error`)
+}
+
+func TestDisableEnv(t *testing.T) {
+	t.Setenv("KNAVIGATOR_TEST_ENV", "value")
+
+	tpl, err := ParseTemplate("test", `{{ env "KNAVIGATOR_TEST_ENV" }}`, DisableEnv(TemplateFuncs(rand.New(rand.NewSource(1)))), "")
+	require.NoError(t, err)
+
+	err = tpl.Execute(new(bytes.Buffer), nil)
+	require.ErrorContains(t, err, "env is disabled for remote workflows")
+}
@@ -56,25 +56,33 @@ func ExecTemplate(tpl *template.Template, params map[string]interface{}) ([]byte
 	return dat, nil
 }
 
-var objID int64
+// Counter generates incrementing object IDs; each workflow run has its own counter
+type Counter struct {
+	val int64
+}
 
-func SetObjectID(val int64) {
-	atomic.StoreInt64(&objID, val)
+// NewCounter returns a counter that starts counting after the given value
+func NewCounter(start int64) *Counter {
+	return &Counter{val: start}
 }
 
-func GenerateNames(pattern string, n int, params map[string]interface{}) ([]string, error) {
-	names := make([]string, n)
-	if len(pattern) != 0 {
-		tpl, err := template.New("name").Parse(pattern)
-		if err != nil {
-			return nil, err
-		}
+// Next increments the counter and returns the new value
+func (c *Counter) Next() int64 {
+	return atomic.AddInt64(&c.val, 1)
+}
 
+// GenerateNames executes the name template n times. For each name, it sets the '_ENUM_' parameter
+// to the next value of the counter, and the '_INDEX_' parameter to the index of the name in the batch.
+// If the template is nil, the names are empty.
+func GenerateNames(tpl *template.Template, n int, counter *Counter, params map[string]interface{}) ([]string, error) {
+	names := make([]string, n)
+	if tpl != nil {
 		for i := 0; i < n; i++ {
-			params["_ENUM_"] = atomic.AddInt64(&objID, 1)
+			params["_ENUM_"] = counter.Next()
+			params["_INDEX_"] = i
 			buf := new(bytes.Buffer)
 			if err := tpl.Execute(buf, params); err != nil {
-				return nil, fmt.Errorf("failed to execute template %s: %v", pattern, err)
+				return nil, fmt.Errorf("failed to execute template %s: %v", tpl.Root.String(), err)
 			}
 			names[i] = buf.String()
 		}
@@ -20,6 +20,7 @@ import (
 	"flag"
 	"regexp"
 	"testing"
+	"text/template"
 
 	"github.com/stretchr/testify/require"
 	"k8s.io/klog/v2"
@@ -92,12 +93,24 @@ func TestGenerateNames(t *testing.T) {
 			params:  make(map[string]interface{}),
 			names:   []string{"name6", "name7", "name8"},
 		},
+		{
+			name:    "Case 4: index in batch",
+			pattern: "name{{._ENUM_}}-{{._INDEX_}}",
+			size:    2,
+			params:  make(map[string]interface{}),
+			names:   []string{"name6-0", "name7-1"},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			SetObjectID(5)
-			names, err := GenerateNames(tc.pattern, tc.size, tc.params)
+			var tpl *template.Template
+			if len(tc.pattern) != 0 {
+				var err error
+				tpl, err = template.New("name").Parse(tc.pattern)
+				require.NoError(t, err)
+			}
+			names, err := GenerateNames(tpl, tc.size, NewCounter(5), tc.params)
 			if len(tc.err) != 0 {
 				require.EqualError(t, err, tc.err)
 			} else {
@@ -142,3 +155,10 @@ func TestExp2Regexp(t *testing.T) {
 		})
 	}
 }
+
+func TestCounter(t *testing.T) {
+	c1, c2 := NewCounter(0), NewCounter(10)
+	require.Equal(t, int64(1), c1.Next())
+	require.Equal(t, int64(11), c2.Next())
+	require.Equal(t, int64(2), c1.Next())
+}